@@ -0,0 +1,334 @@
+package main
+
+import (
+	"ChitChat/clock"
+	proto "ChitChat/grpc"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Response headers Subscribe uses to hand out the session ID and its secret token.
+// The token goes back as request metadata on Publish and Leave
+const (
+	sessionHeader = "chitchat-session"
+	tokenHeader   = "chitchat-token"
+)
+
+// publishAttempts is how often a message is sent when the server does not answer in time
+const publishAttempts = 3
+
+// chatClient is one participant: the servers it can talk to, its Lamport clock and the room it is in
+type chatClient struct {
+	id         string
+	lamport    *clock.Lamport // ticked on our own events and merged on every receive
+	holdBack   time.Duration
+	maxBackoff time.Duration // longest wait between reconnect attempts
+	quietAfter time.Duration // warn when nothing, not even a heartbeat, arrived for this long
+	overChat   bool          // use the Chat stream instead of Subscribe, Publish and Leave
+
+	mutex   sync.Mutex
+	room    *roomSession // nil until the first join
+	servers []endpoint   // in the order they are tried
+	active  int          // index of the server we talk to
+}
+
+// roomSession is the subscription to one room. Vector clocks are per room,
+// so each session gets its own vector and delivery buffer
+type roomSession struct {
+	name     string
+	id       string // session ID from the server, set once the stream is up
+	token    string // proves to the server that we own this session
+	cancel   context.CancelFunc
+	vector   *clock.Vector // only compared against when the server runs in vector mode
+	delivery *deliveryBuffer
+
+	connected bool          // the stream is up
+	lastSeen  int64         // highest logical time received, reconnects resume after it
+	outbox    []outgoing    // typed while disconnected, sent once we are back
+	retryIn   time.Duration // reconnect hint from a SERVER_SHUTDOWN, used once
+	lastHeard time.Time     // when the last broadcast or heartbeat arrived
+	events    *eventStream  // Chat transport: the open stream, nil while disconnected
+
+	posts       map[string]string // message ID -> text as shown, so an edit can say what it replaced
+	replies     map[string]int    // message ID -> replies seen so far
+	privateWith map[string]string // message ID of a private message -> the other person in it
+	typing      map[string]bool   // who is typing right now
+	typingSent  time.Time         // when we last said we are typing, zero once we stopped
+	lastKey     time.Time         // when we last typed while composing
+	delivered   []string          // message IDs to acknowledge as delivered
+	unread      []string          // message IDs shown since we last looked
+	read        []string          // message IDs to acknowledge as read
+}
+
+func newChatClient(id string, servers []endpoint, holdBack, maxBackoff, quietAfter time.Duration, overChat bool) *chatClient {
+	return &chatClient{
+		id:         id,
+		servers:    servers,
+		lamport:    clock.NewLamport(0),
+		holdBack:   holdBack,
+		maxBackoff: maxBackoff,
+		quietAfter: quietAfter,
+		overChat:   overChat,
+	}
+}
+
+// current returns the room we are in, or nil
+func (c *chatClient) current() *roomSession {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.room
+}
+
+// join leaves the current room (if any) and subscribes to another one.
+// lastN and since ask the server to replay history first
+func (c *chatClient) join(name string, lastN int, since int64) {
+	c.leave()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &roomSession{
+		name:        name,
+		cancel:      cancel,
+		vector:      clock.NewVector(),
+		posts:       make(map[string]string),
+		replies:     make(map[string]int),
+		privateWith: make(map[string]string),
+		typing:      make(map[string]bool),
+	}
+	session.delivery = newDeliveryBuffer(c.id, c.holdBack, func(broadcast *proto.BroadCast) {
+		c.show(session, broadcast)
+	})
+	go session.delivery.run(ctx)
+	go c.receiptLoop(ctx, session)
+	go c.typingLoop(ctx, session)
+
+	c.mutex.Lock()
+	c.room = session
+	c.mutex.Unlock()
+
+	//Launch goroutine for handling incoming broadcast messages
+	//Runs independently form the main input loop
+	go c.subscribeLoop(ctx, session, lastN, since)
+}
+
+// leave tells the server we left the current room and stops its subscription
+func (c *chatClient) leave() {
+	c.mutex.Lock()
+	session := c.room
+	c.room = nil
+	c.mutex.Unlock()
+	if session == nil {
+		return
+	}
+
+	//Only this device leaves, our other sessions stay in the room
+	err := c.leaveRequest(session, &proto.LeaveRequest{
+		ClientId:  c.id,
+		Room:      session.name,
+		Timestamp: c.lamport.Tick(),
+	})
+	if err != nil {
+		log.Printf("Client LEAVE_RPC_ERROR: %v", err)
+	}
+	session.cancel()
+	session.delivery.report()
+}
+
+// authContext returns a context carrying the session token, and the session ID
+func (c *chatClient) authContext(session *roomSession) (context.Context, string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return metadata.AppendToOutgoingContext(context.Background(), tokenHeader, session.token), session.id
+}
+
+// publish sends a chat message to the current room, or only to the recipient if
+// one is given. parent is the ID of the message it replies to, if any
+func (c *chatClient) publish(text, recipient, parent string) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client PUBLISH_ERROR: not in a room, use /join <room>")
+		return
+	}
+	//The server would refuse it, and gRPC cannot even marshal it
+	if !utf8.ValidString(text) {
+		log.Printf("Client PUBLISH_ERROR: message is not valid UTF-8")
+		return
+	}
+	id, err := newMessageID()
+	if err != nil {
+		log.Printf("Client PUBLISH_ERROR: failed to create message ID: %v", err)
+		return
+	}
+	//Sending is a local event, so tick before the clock travels with the message.
+	//The message keeps its time for every attempt, a resend is not another event
+	message := outgoing{id: id, text: text, recipient: recipient, parent: parent, timestamp: c.lamport.Tick()}
+	//Private messages are not part of the rooms causal history. Our own entry
+	//is counted by the server, so a rejected message leaves no gap in it
+	if recipient == "" {
+		message.vector = session.vector.Snapshot()
+	}
+	if c.queue(session, message) {
+		return
+	}
+	c.send(session, message)
+}
+
+// send publishes one message right now. If the server cannot be reached
+// the message goes to the outbox and is sent after reconnecting
+func (c *chatClient) send(session *roomSession, message outgoing) {
+	text, recipient, sendTime := message.text, message.recipient, message.timestamp
+
+	//Send chat message to server using Publish RPC or the Chat stream
+	req := &proto.PublishRequest{
+		ClientId:  c.id,
+		Text:      text,
+		Timestamp: sendTime,
+		Vector:    message.vector,
+		Room:      session.name,
+		Recipient: recipient,
+		MessageId: message.id,
+		ParentId:  message.parent,
+	}
+	response, err := c.publishRequest(session, req)
+	//The server may have posted it and only the answer got lost, the message ID makes trying again safe
+	for attempt := 2; status.Code(err) == codes.DeadlineExceeded && attempt <= publishAttempts; attempt++ {
+		log.Printf("Client PUBLISH_RETRY: message_id=%s attempt=%d", message.id, attempt)
+		response, err = c.publishRequest(session, req)
+	}
+	if status.Code(err) == codes.Unavailable {
+		c.setConnected(session, false)
+		c.queue(session, message)
+		return
+	}
+	if err != nil {
+		log.Printf("Client PUBLISH_ERROR: %v", err)
+		return
+	}
+	if !response.Ack && response.ErrorCode != proto.PublishError_NO_ERROR {
+		log.Printf("Client PUBLISH_REJECTED: code=%s reason=%s", response.ErrorCode, response.Error)
+		return
+	}
+	if !response.Ack {
+		log.Printf("Client PUBLISH_REJECTED: reason=%s", response.Error)
+		return
+	}
+	if recipient != "" {
+		log.Printf("Client DIRECT_SENT: id=%s to=%s local_time=%d message_id=%s content=%q", c.id, recipient, sendTime, response.MessageId, text)
+		return
+	}
+	log.Printf("Client PUBLISH_SENT: id=%s room=%s local_time=%d message_id=%s content=%q", c.id, session.name, sendTime, response.MessageId, text)
+}
+
+// newMessageID picks the ID of a message we send. The server rejects an ID someone else
+// already used in the room, so it has to be unique among everyones messages
+func newMessageID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// listRooms prints every room on the server
+func (c *chatClient) listRooms() {
+	response, err := c.server().ListRooms(context.Background(), &proto.ListRoomsRequest{})
+	if err != nil {
+		log.Printf("Client ROOMS_ERROR: %v", err)
+		return
+	}
+	for _, room := range response.Rooms {
+		log.Printf("Client ROOM: %s members=%d", room.Name, room.Members)
+	}
+}
+
+// createRoom makes sure a room exists before we join it
+func (c *chatClient) createRoom(name string) error {
+	_, err := c.server().CreateRoom(context.Background(), &proto.CreateRoomRequest{Name: name})
+	return err
+}
+
+// show prints a broadcast once the delivery buffer says its causal predecessors were shown
+func (c *chatClient) show(session *roomSession, broadcast *proto.BroadCast) {
+	localTime := c.lamport.Now()
+
+	//Label messages nobody here had seen when they were sent
+	label := ""
+	if len(broadcast.Vector) > 0 {
+		if broadcast.Type == proto.BroadCast_CHAT && broadcast.ClientId != c.id &&
+			clock.Compare(broadcast.Vector, session.vector.Snapshot()) == clock.Concurrent {
+			label = " [concurrent]"
+		}
+		session.vector.Merge(broadcast.Vector)
+	}
+
+	switch broadcast.Type {
+	case proto.BroadCast_CHAT:
+		session.posts[broadcast.MessageId] = broadcast.Message
+		c.typingChanged(session, broadcast.ClientId, false)
+		c.received(session, broadcast)
+		label += c.threadLabel(session, broadcast) + reactionLabel(broadcast.Reactions)
+		log.Printf("Client BROADCAST received: room=%s from %s logical_time=%d local_time=%d message_id=%s content=%q%s",
+			broadcast.Room, broadcast.ClientId, broadcast.Timestamp, localTime, broadcast.MessageId, content(broadcast), label)
+
+	case proto.BroadCast_EDIT:
+		was := session.posts[broadcast.MessageId]
+		session.posts[broadcast.MessageId] = broadcast.Message
+		log.Printf("Client EDITED: room=%s message_id=%s by %s logical_time=%d local_time=%d content=%q (was %q)",
+			broadcast.Room, broadcast.MessageId, broadcast.ClientId, broadcast.Timestamp, localTime, broadcast.Message, was)
+
+	case proto.BroadCast_DELETE:
+		delete(session.posts, broadcast.MessageId)
+		log.Printf("Client DELETED: room=%s message_id=%s by %s logical_time=%d local_time=%d content=%q",
+			broadcast.Room, broadcast.MessageId, broadcast.ClientId, broadcast.Timestamp, localTime, "[deleted]")
+
+	case proto.BroadCast_REACTION:
+		action := "added"
+		if broadcast.Removed {
+			action = "removed"
+		}
+		log.Printf("Client REACTION: room=%s message_id=%s %s %s %s logical_time=%d local_time=%d content=%q reactions=[%s]",
+			broadcast.Room, broadcast.MessageId, broadcast.ClientId, action, broadcast.Reaction, broadcast.Timestamp, localTime,
+			session.posts[broadcast.MessageId], formatReactions(broadcast.Reactions))
+
+	case proto.BroadCast_PRESENCE:
+		log.Printf("Client PRESENCE: %s is %s in room %s at logical_time=%d local_time=%d",
+			broadcast.ClientId, statusName(broadcast.Status), broadcast.Room, broadcast.Timestamp, localTime)
+
+	case proto.BroadCast_LEAVE:
+		reason := ""
+		if broadcast.Message != "" {
+			reason = " (" + broadcast.Message + ")"
+		}
+		log.Printf("Client BROADCAST: %s left room %s at logical_time=%d local_time=%d%s",
+			broadcast.ClientId, broadcast.Room, broadcast.Timestamp, localTime, reason)
+
+	case proto.BroadCast_DIRECT:
+		session.posts[broadcast.MessageId] = broadcast.Message
+		other := broadcast.ClientId
+		if other == c.id {
+			other = broadcast.Recipient
+		}
+		c.mutex.Lock()
+		session.privateWith[broadcast.MessageId] = other
+		c.mutex.Unlock()
+		c.received(session, broadcast)
+		log.Printf("Client DIRECT received: from %s to %s logical_time=%d local_time=%d message_id=%s content=%q%s",
+			broadcast.ClientId, broadcast.Recipient, broadcast.Timestamp, localTime, broadcast.MessageId, content(broadcast), c.threadLabel(session, broadcast)+reactionLabel(broadcast.Reactions))
+
+	case proto.BroadCast_JOIN:
+		log.Printf("Client BROADCAST: %s joined room %s at logical_time=%d local_time=%d",
+			broadcast.ClientId, broadcast.Room, broadcast.Timestamp, localTime)
+
+	default:
+		log.Printf("Client BROADCAST: unknown type from %s at logical_time=%d",
+			broadcast.ClientId, broadcast.Timestamp)
+	}
+}
@@ -2,8 +2,6 @@ package main
 
 import (
 	proto "ChitChat/grpc"
-	"bufio"
-	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -12,106 +10,213 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func main() {
 	var serverAddr string
 	var clientID string
-
-	flag.StringVar(&serverAddr, "server", "localhost:50051", "gRPC server address")
+	var lastN int
+	var since int64
+	var holdBack time.Duration
+	var room string
+	var certFile, keyFile, caFile string
+	var maxBackoff time.Duration
+	var quietAfter time.Duration
+	var transport string
+
+	flag.StringVar(&serverAddr, "server", "localhost:50051", "gRPC server address, or a comma separated list to fail over between (primary first)")
 	flag.StringVar(&clientID, "id", "", "Client ID (required)")
+	flag.StringVar(&room, "room", "general", "Room to join on startup")
+	flag.IntVar(&lastN, "last", 0, "Replay the last N messages from the chat history when joining")
+	flag.Int64Var(&since, "since", 0, "Replay every message after this logical time when joining")
+	flag.StringVar(&certFile, "cert", "", "Client certificate (PEM) for mutual TLS, its CN is used as the ID")
+	flag.StringVar(&keyFile, "key", "", "Client private key (PEM) for mutual TLS")
+	flag.StringVar(&caFile, "ca", "", "CA bundle (PEM) to verify the server with, enables TLS")
+	flag.DurationVar(&maxBackoff, "max-backoff", 30*time.Second, "Longest wait between attempts to reconnect to the server")
+	flag.DurationVar(&holdBack, "holdback", 250*time.Millisecond, "Longest time a message is held back waiting for its causal predecessors")
+	flag.DurationVar(&quietAfter, "heartbeat-timeout", 15*time.Second, "Warn when no heartbeat arrived from the server for this long (0 turns the warning off)")
+	flag.StringVar(&transport, "transport", "subscribe", "How to talk to the server: subscribe (Subscribe stream with Publish and Leave calls) or chat (one Chat stream)")
 	flag.Parse()
 
-	if clientID == "" {
-		fmt.Fprintln(os.Stderr, "client id is required: -id <name>")
+	if transport != "subscribe" && transport != "chat" {
+		fmt.Fprintf(os.Stderr, "unknown transport %q (use subscribe or chat)\n", transport)
 		os.Exit(2)
 	}
 
-	//Establish gRPC connection to server
-	conn, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, commonName, err := transportCredentials(certFile, keyFile, caFile)
 	if err != nil {
-		log.Fatalf("Not working")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	defer conn.Close() //Connection is closed when main func exits
-
-	//Create gRPC client stub from the proto file
-	client := proto.NewChitChatClient(conn)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	subreq := &proto.SubscribeRequest{Id: clientID}
-
-	//Launch goroutine for handling incoming broadcast messages
-	//Runs independently form the main input loop below
-	go func() {
-		//Server-side streaming connection for real-time updates
-		//Runs independently from the main input loop below
-		stream, _ := client.Subscribe(ctx, subreq)
-
-		for {
-			broadcast, err := stream.Recv() // Receive broadcasts
-			if err != nil {
-				return
-			} // Returns broadcasts to clients
-			switch broadcast.Type {
-			case proto.BroadCast_CHAT:
-				log.Printf("Client BROADCAST received: from %s logical_time=%d content=%q",
-					broadcast.ClientId, broadcast.Timestamp, broadcast.Message)
-
-			case proto.BroadCast_LEAVE:
-				log.Printf("Client BROADCAST: %s left the chat at logical_time=%d",
-					broadcast.ClientId, broadcast.Timestamp)
-
-			case proto.BroadCast_JOIN:
-				log.Printf("Client BROADCAST: %s joined the chat at logical_time=%d",
-					broadcast.ClientId, broadcast.Timestamp)
-
-			default:
-				log.Printf("Client BROADCAST: unknown type from %s at logical_time=%d",
-					broadcast.ClientId, broadcast.Timestamp)
-			}
+	//With a client certificate the server only accepts its CN as our ID
+	if clientID == "" {
+		clientID = commonName
+	}
+	if commonName != "" && clientID != commonName {
+		fmt.Fprintf(os.Stderr, "id %s does not match the certificate CN %s\n", clientID, commonName)
+		os.Exit(2)
+	}
+
+	if clientID == "" {
+		fmt.Fprintln(os.Stderr, "client id is required: -id <name>")
+		os.Exit(2)
+	}
+
+	//Establish gRPC connection to every server, and create a client stub from the proto file for each
+	var servers []endpoint
+	for _, addr := range strings.Split(serverAddr, ",") {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			log.Fatalf("Not working: %s: %v", addr, err)
 		}
-	}()
+		defer conn.Close() //Connection is closed when main func exits
+		servers = append(servers, endpoint{addr: addr, rpc: proto.NewChitChatClient(conn)})
+	}
+
+	client := newChatClient(clientID, servers, holdBack, maxBackoff, quietAfter, transport == "chat")
+	client.join(room, lastN, since)
 
 	//Main input loop
 	//Runs in the main goroutine
-	stdin := bufio.NewScanner(os.Stdin)
-	fmt.Println("Type messages and press Enter to publish. Type '/leave' to exit.")
+	var draft []string // lines of a message that goes on in the next line
+	stdin, restore := newInput(func(typing bool) {
+		client.composing(typing || draft != nil)
+	})
+	defer restore()
+	fmt.Println("Type messages and press Enter to publish. Type '/join <room>' to switch room, '/rooms' to list them, '/who' to see who is here, '/away' and '/back' to set your status, '/msg <id> <text>' to whisper, '/reply <msgid> <text>' to answer a message, '/thread <msgid>' to read its replies, '/react <msgid> <emoji>' or '/unreact <msgid> <emoji>' to react, '/receipts <msgid>' to see who read it, '/edit <msgid> <text>' or '/delete <msgid>' to change what you sent and '/leave' to exit. End a line with \\ to go on in the next one, '/cancel' drops what you wrote so far.")
 	for stdin.Scan() {
 		line := stdin.Text()
 		line = strings.TrimSpace(line)
-		if line == "" {
+		//Whoever types has seen what is on the screen
+		client.looked()
+		//A message ending in \ goes on in the next line, commands in between still run
+		if !strings.HasPrefix(line, "/") {
+			if text, more := strings.CutSuffix(line, `\`); more {
+				draft = append(draft, strings.TrimSpace(text))
+				client.composing(true)
+				continue
+			}
+			if draft != nil {
+				line = strings.TrimSpace(strings.Join(append(draft, line), "\n"))
+				draft = nil
+			}
+		}
+		if line == "/cancel" {
+			draft = nil
+		}
+		//The others see us typing until the message is sent or dropped
+		client.composing(draft != nil)
+		if line == "" || line == "/cancel" {
 			continue
 		}
 		//Handle exit when user types /leave
 		if line == "/leave" {
 			// call Leave RPC then exit
-			_, err := client.Leave(context.Background(), &proto.LeaveRequest{ClientId: clientID})
-			if err != nil {
-				log.Printf("Client LEAVE_RPC_ERROR: %v", err)
-			}
+			client.leave()
 			log.Printf("Client SHUTDOWN: id=%s initiated leave", clientID)
 			// Sleep briefly to allow leave broadcast to flow and then exit
 			time.Sleep(200 * time.Millisecond)
 			return
 		}
 
-		//Send chat message to server using Publish RPC
-		response, err := client.Publish(context.Background(), &proto.PublishRequest{ClientId: clientID, Text: line})
-		if err != nil {
-			log.Printf("Client PUBLISH_ERROR: %v", err)
+		if line == "/rooms" {
+			client.listRooms()
+			continue
+		}
+
+		if messageID, ok := strings.CutPrefix(line, "/receipts "); ok {
+			client.receipts(strings.TrimSpace(messageID))
+			continue
+		}
+
+		if line == "/who" {
+			client.who()
+			continue
+		}
+
+		if line == "/away" || line == "/back" {
+			client.setStatus(line == "/away")
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(line, "/join "); ok {
+			name = strings.TrimSpace(name)
+			//Create the room first, it is fine if it already exists
+			if err := client.createRoom(name); err != nil && status.Code(err) != codes.AlreadyExists {
+				log.Printf("Client JOIN_ERROR: %v", err)
+				continue
+			}
+			client.join(name, lastN, 0)
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "/msg "); ok {
+			recipient, text, found := strings.Cut(strings.TrimSpace(rest), " ")
+			if !found || strings.TrimSpace(text) == "" {
+				log.Printf("Client DIRECT_ERROR: usage /msg <id> <text>")
+				continue
+			}
+			client.publish(strings.TrimSpace(text), recipient, "")
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "/edit "); ok {
+			messageID, text, found := strings.Cut(strings.TrimSpace(rest), " ")
+			if !found || strings.TrimSpace(text) == "" {
+				log.Printf("Client EDIT_ERROR: usage /edit <msgid> <text>")
+				continue
+			}
+			client.edit(messageID, strings.TrimSpace(text))
 			continue
 		}
-		if !response.Ack {
-			log.Printf("Client PUBLISH_REJECTED: reason=%s", response.Error)
+
+		if messageID, ok := strings.CutPrefix(line, "/delete "); ok {
+			client.remove(strings.TrimSpace(messageID))
 			continue
 		}
-		log.Printf("Client PUBLISH_SENT: id=%s content=%q", clientID, line)
+
+		if rest, ok := strings.CutPrefix(line, "/reply "); ok {
+			parentID, text, found := strings.Cut(strings.TrimSpace(rest), " ")
+			if !found || strings.TrimSpace(text) == "" {
+				log.Printf("Client REPLY_ERROR: usage /reply <msgid> <text>")
+				continue
+			}
+			client.reply(parentID, strings.TrimSpace(text))
+			continue
+		}
+
+		if messageID, ok := strings.CutPrefix(line, "/thread "); ok {
+			client.thread(strings.TrimSpace(messageID))
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "/react "); ok {
+			messageID, reaction, found := strings.Cut(strings.TrimSpace(rest), " ")
+			if !found || strings.TrimSpace(reaction) == "" {
+				log.Printf("Client REACTION_ERROR: usage /react <msgid> <reaction>")
+				continue
+			}
+			client.react(messageID, strings.TrimSpace(reaction), false)
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "/unreact "); ok {
+			messageID, reaction, found := strings.Cut(strings.TrimSpace(rest), " ")
+			if !found || strings.TrimSpace(reaction) == "" {
+				log.Printf("Client REACTION_ERROR: usage /unreact <msgid> <reaction>")
+				continue
+			}
+			client.react(messageID, strings.TrimSpace(reaction), true)
+			continue
+		}
+
+		client.publish(line, "", "")
 	}
 
 	if stdin.Err() != nil {
 		log.Printf("Client STDIN_ERROR: %v", stdin.Err())
 	}
+	client.leave()
 }
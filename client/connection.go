@@ -0,0 +1,266 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	initialBackoff = 500 * time.Millisecond
+	outboxSize     = 100 // messages kept while disconnected, older ones are dropped
+)
+
+// outgoing is a message we send, or typed while we were disconnected and send later
+type outgoing struct {
+	id        string // message ID, the same for every attempt so the server posts it once
+	text      string
+	recipient string
+	parent    string           // message ID it replies to, if any
+	timestamp int64            // Lamport time it was written at
+	vector    map[string]int64 // what we had seen when it was written, nil for a private message
+}
+
+// subscribeLoop keeps the room subscription alive until ctx is cancelled or we leave.
+// When the stream breaks it reconnects with exponential backoff and jitter, and resumes
+// from the last broadcast we saw so nothing is missed or shown twice
+func (c *chatClient) subscribeLoop(ctx context.Context, session *roomSession, lastN int, since int64) {
+	backoff := initialBackoff
+	subreq := &proto.SubscribeRequest{Id: c.id, Room: session.name, LastN: int32(lastN), SinceTimestamp: since}
+
+	receive := c.receive
+	if c.overChat {
+		receive = c.receiveChat
+	}
+
+	for {
+		connected, err := receive(ctx, session, subreq)
+		if ctx.Err() != nil || c.current() != session {
+			return
+		}
+		c.setConnected(session, false)
+		if err != nil {
+			log.Printf("Client SUBSCRIBE_ERROR: room=%s %v", session.name, err)
+		}
+
+		//A stream that worked for a while starts the backoff over,
+		//a server that does not answer at all makes us try the next one
+		if connected {
+			backoff = initialBackoff
+		} else {
+			c.failover()
+		}
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		//A server that went away on purpose told us when to come back
+		if hint := c.retryHint(session); hint > 0 {
+			wait = hint + time.Duration(rand.Int63n(int64(hint/2)+1))
+		}
+		log.Printf("Client CONNECTION: room=%s state=reconnecting in=%s", session.name, wait.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		backoff *= 2
+		if backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+
+		//Resume after the last broadcast we got instead of replaying the history again
+		subreq = &proto.SubscribeRequest{Id: c.id, Room: session.name, SinceTimestamp: c.lastSeen(session)}
+	}
+}
+
+// receive runs one Subscribe stream until it ends. connected tells whether the
+// stream got as far as the response header
+func (c *chatClient) receive(ctx context.Context, session *roomSession, subreq *proto.SubscribeRequest) (connected bool, err error) {
+	//Everything started for this stream stops with it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	//Server-side streaming connection for real-time updates
+	stream, err := c.server().Subscribe(ctx, subreq)
+	if err != nil {
+		return false, err
+	}
+	//The server names this device's session in the response header
+	header, err := stream.Header()
+	if err == nil && len(header.Get(sessionHeader)) == 0 {
+		//Turned away before the stream started (e.g. by a backup), the reason comes with its end
+		_, err = stream.Recv()
+	}
+	if err != nil {
+		return false, err
+	}
+	c.attached(ctx, session, header)
+
+	for {
+		broadcast, err := stream.Recv() // Receive broadcasts
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// The server closed the stream, after our Leave or because it kicked us
+				return true, nil
+			}
+			return true, err
+		}
+		c.consume(session, broadcast)
+	}
+}
+
+// attached takes the session ID and token from the response header of a new stream,
+// marks the room connected and sends what was typed in the meantime.
+// ctx must end with the stream
+func (c *chatClient) attached(ctx context.Context, session *roomSession, header metadata.MD) {
+	c.mutex.Lock()
+	if ids := header.Get(sessionHeader); len(ids) > 0 {
+		session.id = ids[0]
+	}
+	if tokens := header.Get(tokenHeader); len(tokens) > 0 {
+		session.token = tokens[0]
+	}
+	session.lastHeard = time.Now()
+	c.mutex.Unlock()
+	c.setConnected(session, true)
+
+	//Send what was typed while we were away, in the background so we keep receiving
+	go c.flush(session)
+	go c.watchHeartbeats(ctx, session)
+}
+
+// consume handles one broadcast from the stream
+func (c *chatClient) consume(session *roomSession, broadcast *proto.BroadCast) {
+	c.heard(session)
+	if broadcast.Type == proto.BroadCast_HEARTBEAT {
+		return
+	}
+	if broadcast.Type == proto.BroadCast_SERVER_SHUTDOWN {
+		//Not part of the chat, so it skips the clocks and the delivery buffer
+		retryIn := time.Duration(broadcast.ReconnectAfterMs) * time.Millisecond
+		log.Printf("Client SERVER_SHUTDOWN: room=%s reason=%q reconnect_in=%s", session.name, broadcast.Message, retryIn)
+		c.mutex.Lock()
+		session.retryIn = retryIn
+		c.mutex.Unlock()
+		return
+	}
+	if broadcast.Type == proto.BroadCast_RECEIPT {
+		c.showReceipt(session, broadcast)
+		return
+	}
+	if broadcast.Type == proto.BroadCast_TYPING_START || broadcast.Type == proto.BroadCast_TYPING_STOP {
+		//Neither part of the chat nor of its history
+		c.typingChanged(session, broadcast.ClientId, broadcast.Type == proto.BroadCast_TYPING_START)
+		return
+	}
+	c.lamport.Merge(broadcast.Timestamp)
+	c.mutex.Lock()
+	if broadcast.Timestamp > session.lastSeen {
+		session.lastSeen = broadcast.Timestamp
+	}
+	c.mutex.Unlock()
+	session.delivery.add(broadcast)
+}
+
+// heard notes that the server is still talking to us, and says so if it had gone quiet
+func (c *chatClient) heard(session *roomSession) {
+	c.mutex.Lock()
+	quiet := c.quietAfter > 0 && time.Since(session.lastHeard) > c.quietAfter
+	session.lastHeard = time.Now()
+	c.mutex.Unlock()
+
+	if quiet {
+		log.Printf("Client HEARTBEAT: room=%s the server is talking again", session.name)
+	}
+}
+
+// watchHeartbeats warns once when the server sends nothing, not even a heartbeat,
+// for longer than quietAfter. The stream itself is left alone, gRPC decides when it is dead
+func (c *chatClient) watchHeartbeats(ctx context.Context, session *roomSession) {
+	if c.quietAfter <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.quietAfter / 3)
+	defer ticker.Stop()
+
+	warned := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.mutex.Lock()
+		lastHeard := session.lastHeard
+		c.mutex.Unlock()
+		if time.Since(lastHeard) > c.quietAfter && !lastHeard.Equal(warned) {
+			warned = lastHeard
+			log.Printf("Client HEARTBEAT_MISSED: room=%s nothing from the server for %s, the connection may be dead",
+				session.name, time.Since(lastHeard).Round(time.Second))
+		}
+	}
+}
+
+// setConnected records and shows the connection state of a room
+func (c *chatClient) setConnected(session *roomSession, connected bool) {
+	c.mutex.Lock()
+	changed := session.connected != connected
+	session.connected = connected
+	c.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+	if connected {
+		log.Printf("Client CONNECTION: room=%s state=connected", session.name)
+	} else {
+		log.Printf("Client CONNECTION: room=%s state=disconnected, messages are buffered until we are back", session.name)
+	}
+}
+
+// retryHint returns the reconnect hint from a SERVER_SHUTDOWN and forgets it
+func (c *chatClient) retryHint(session *roomSession) time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	hint := session.retryIn
+	session.retryIn = 0
+	return hint
+}
+
+func (c *chatClient) lastSeen(session *roomSession) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return session.lastSeen
+}
+
+// queue keeps a message for later while we are disconnected.
+// It returns false if we are connected and the message should be sent now
+func (c *chatClient) queue(session *roomSession, message outgoing) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if session.connected {
+		return false
+	}
+	if len(session.outbox) == outboxSize {
+		session.outbox = session.outbox[1:]
+	}
+	session.outbox = append(session.outbox, message)
+	log.Printf("Client PUBLISH_QUEUED: room=%s waiting=%d content=%q", session.name, len(session.outbox), message.text)
+	return true
+}
+
+// flush sends the messages buffered while we were disconnected, in the order they were typed
+func (c *chatClient) flush(session *roomSession) {
+	c.mutex.Lock()
+	outbox := session.outbox
+	session.outbox = nil
+	c.mutex.Unlock()
+
+	for _, message := range outbox {
+		c.send(session, message)
+	}
+}
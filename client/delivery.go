@@ -0,0 +1,186 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// heldBroadcast is a broadcast waiting in the hold-back queue
+type heldBroadcast struct {
+	broadcast *proto.BroadCast
+	arrived   time.Time
+}
+
+// deliveryBuffer holds back received broadcasts until their causal predecessors
+// have been delivered, so messages are shown in causal order even if they
+// arrive out of order (e.g. from more than one server replica).
+//
+// With vector clocks a CHAT from j is delivered once V[j] == delivered[j]+1 and
+// V[k] <= delivered[k] for every other k. The other types carry the servers merged
+// view and wait until everything in it was delivered.
+// With only Lamport timestamps every broadcast names the one the whole room got
+// before it, and is delivered as soon as that one was. Only a real gap is waited for.
+//
+// Nothing waits longer than the hold-back timeout, anything released after that
+// is delivered out of order and counted in the report.
+type deliveryBuffer struct {
+	mutex    sync.Mutex
+	self     string
+	holdBack time.Duration
+	deliver  func(*proto.BroadCast)
+
+	pending   []heldBroadcast
+	delivered map[string]int64 // vector mode: messages delivered per sender
+	baseline  bool             // vector mode: our own JOIN gave us the servers view
+	lastTime  int64            // highest Lamport time delivered
+	roomTime  int64            // highest Lamport time delivered of a broadcast to the whole room
+
+	total      int
+	outOfOrder int
+}
+
+func newDeliveryBuffer(self string, holdBack time.Duration, deliver func(*proto.BroadCast)) *deliveryBuffer {
+	return &deliveryBuffer{
+		self:      self,
+		holdBack:  holdBack,
+		deliver:   deliver,
+		delivered: make(map[string]int64),
+	}
+}
+
+// add takes a broadcast straight from stream.Recv
+func (d *deliveryBuffer) add(broadcast *proto.BroadCast) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(broadcast.Vector) > 0 && broadcast.Type == proto.BroadCast_CHAT && d.baseline &&
+		broadcast.Vector[broadcast.ClientId] <= d.delivered[broadcast.ClientId] {
+		// Already delivered, a second replica sent it again
+		return
+	}
+
+	d.pending = append(d.pending, heldBroadcast{broadcast: broadcast, arrived: time.Now()})
+	d.release(time.Now())
+}
+
+// run releases broadcasts whose hold-back timeout ran out, until ctx is cancelled
+func (d *deliveryBuffer) run(ctx context.Context) {
+	interval := d.holdBack / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.mutex.Lock()
+			d.release(now)
+			d.mutex.Unlock()
+		}
+	}
+}
+
+// report logs how many broadcasts were delivered and how many of them out of order
+func (d *deliveryBuffer) report() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	log.Printf("Client DELIVERY_REPORT: delivered=%d out_of_order=%d still_held=%d",
+		d.total, d.outOfOrder, len(d.pending))
+}
+
+// release delivers everything that may be delivered now. Must be called with d.mutex held
+func (d *deliveryBuffer) release(now time.Time) {
+	// Oldest timestamps first, so timed out messages come out in Lamport order
+	sort.SliceStable(d.pending, func(i, j int) bool {
+		return d.pending[i].broadcast.Timestamp < d.pending[j].broadcast.Timestamp
+	})
+
+	for progress := true; progress; {
+		progress = false
+		for i, held := range d.pending {
+			ready := d.ready(held.broadcast)
+			expired := now.Sub(held.arrived) >= d.holdBack
+			if !ready && !expired {
+				continue
+			}
+			d.pending = append(d.pending[:i], d.pending[i+1:]...)
+			d.accept(held.broadcast, !ready)
+			progress = true
+			break
+		}
+	}
+}
+
+// ready tells whether all causal predecessors of the broadcast were delivered
+func (d *deliveryBuffer) ready(broadcast *proto.BroadCast) bool {
+	vector := broadcast.Vector
+	if len(vector) == 0 {
+		// Lamport only: nothing delivered yet means nothing to wait for
+		return d.total == 0 || broadcast.Previous <= d.roomTime
+	}
+	if !d.baseline {
+		// Until our JOIN arrives everything is history replayed in server order
+		return true
+	}
+
+	if broadcast.Type != proto.BroadCast_CHAT {
+		for id, count := range vector {
+			if count > d.delivered[id] {
+				return false
+			}
+		}
+		return true
+	}
+
+	sender := broadcast.ClientId
+	if vector[sender] != d.delivered[sender]+1 {
+		return false
+	}
+	for id, count := range vector {
+		if id != sender && count > d.delivered[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// accept updates the delivery state and hands the broadcast on. Must be called with d.mutex held
+func (d *deliveryBuffer) accept(broadcast *proto.BroadCast, timedOut bool) {
+	outOfOrder := timedOut && len(broadcast.Vector) > 0
+	if broadcast.Timestamp < d.lastTime {
+		outOfOrder = true
+	}
+	if outOfOrder {
+		d.outOfOrder++
+		log.Printf("Client DELIVERY_OUT_OF_ORDER: from %s logical_time=%d (already delivered up to %d)",
+			broadcast.ClientId, broadcast.Timestamp, d.lastTime)
+	}
+	if broadcast.Timestamp > d.lastTime {
+		d.lastTime = broadcast.Timestamp
+	}
+	if broadcast.Recipient == "" && broadcast.Timestamp > d.roomTime {
+		d.roomTime = broadcast.Timestamp
+	}
+
+	if len(broadcast.Vector) > 0 {
+		if broadcast.Type == proto.BroadCast_JOIN && broadcast.ClientId == d.self {
+			d.baseline = true
+		}
+		for id, count := range broadcast.Vector {
+			if count > d.delivered[id] {
+				d.delivered[id] = count
+			}
+		}
+	}
+
+	d.total++
+	d.deliver(broadcast)
+}
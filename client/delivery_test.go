@@ -0,0 +1,64 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"testing"
+	"time"
+)
+
+func TestLamportDelivery(t *testing.T) {
+	chat := func(timestamp, previous int64) *proto.BroadCast {
+		return &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "bob", Timestamp: timestamp, Previous: previous}
+	}
+	direct := func(timestamp, previous int64) *proto.BroadCast {
+		b := chat(timestamp, previous)
+		b.Type, b.Recipient = proto.BroadCast_DIRECT, "alice"
+		return b
+	}
+
+	tests := []struct {
+		name      string
+		arrive    []*proto.BroadCast
+		delivered []int64 // timestamps shown right away, in order
+	}{
+		{"in order", []*proto.BroadCast{chat(1, 0), chat(4, 1), chat(7, 4)}, []int64{1, 4, 7}},
+		{"gap is held", []*proto.BroadCast{chat(1, 0), chat(7, 4)}, []int64{1}},
+		{"gap filled", []*proto.BroadCast{chat(1, 0), chat(7, 4), chat(4, 1)}, []int64{1, 4, 7}},
+		{"private messages do not move the room", []*proto.BroadCast{chat(1, 0), direct(3, 1), chat(5, 1)}, []int64{1, 3, 5}},
+		{"first one is not held", []*proto.BroadCast{chat(40, 37)}, []int64{40}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []int64
+			d := newDeliveryBuffer("alice", time.Hour, func(b *proto.BroadCast) { got = append(got, b.Timestamp) })
+			for _, b := range test.arrive {
+				d.add(b)
+			}
+			if len(got) != len(test.delivered) {
+				t.Fatalf("delivered %v, want %v", got, test.delivered)
+			}
+			for i := range got {
+				if got[i] != test.delivered[i] {
+					t.Fatalf("delivered %v, want %v", got, test.delivered)
+				}
+			}
+		})
+	}
+}
+
+func TestLamportDeliveryTimesOut(t *testing.T) {
+	var got []int64
+	d := newDeliveryBuffer("alice", 10*time.Millisecond, func(b *proto.BroadCast) { got = append(got, b.Timestamp) })
+	d.add(&proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "bob", Timestamp: 1})
+	d.add(&proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "bob", Timestamp: 7, Previous: 4})
+	if len(got) != 1 {
+		t.Fatalf("delivered %v before the hold-back ran out", got)
+	}
+
+	d.mutex.Lock()
+	d.release(time.Now().Add(time.Second))
+	d.mutex.Unlock()
+	if len(got) != 2 || got[1] != 7 {
+		t.Fatalf("delivered %v after the hold-back, want [1 7]", got)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"log"
+)
+
+// edit replaces the text of one of our messages in the current room
+func (c *chatClient) edit(messageID, text string) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client EDIT_ERROR: not in a room, use /join <room>")
+		return
+	}
+	ctx, _ := c.authContext(session)
+	response, err := c.server().EditMessage(ctx, &proto.EditRequest{
+		ClientId:  c.id,
+		Room:      session.name,
+		MessageId: messageID,
+		Text:      text,
+		Timestamp: c.lamport.Tick(),
+	})
+	if err != nil {
+		log.Printf("Client EDIT_ERROR: %v", err)
+		return
+	}
+	if !response.Ack {
+		log.Printf("Client EDIT_REJECTED: code=%s reason=%s", response.ErrorCode, response.Error)
+	}
+}
+
+// remove deletes one of our messages in the current room
+func (c *chatClient) remove(messageID string) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client DELETE_ERROR: not in a room, use /join <room>")
+		return
+	}
+	ctx, _ := c.authContext(session)
+	response, err := c.server().DeleteMessage(ctx, &proto.DeleteRequest{
+		ClientId:  c.id,
+		Room:      session.name,
+		MessageId: messageID,
+		Timestamp: c.lamport.Tick(),
+	})
+	if err != nil {
+		log.Printf("Client DELETE_ERROR: %v", err)
+		return
+	}
+	if !response.Ack {
+		log.Printf("Client DELETE_REJECTED: reason=%s", response.Error)
+	}
+}
+
+// content is how a message reads now, with a tombstone for a deleted one
+func content(broadcast *proto.BroadCast) string {
+	switch {
+	case broadcast.Deleted:
+		return "[deleted]"
+	case broadcast.Edited:
+		return broadcast.Message + " (edited)"
+	}
+	return broadcast.Message
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"log"
+)
+
+// endpoint is one server the client can talk to
+type endpoint struct {
+	addr string
+	rpc  proto.ChitChatClient
+}
+
+// server returns the stub of the server we currently talk to
+func (c *chatClient) server() proto.ChitChatClient {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.servers[c.active].rpc
+}
+
+// failover moves on to the next server in the list, wrapping around at the end.
+// Backups turn us away with Unavailable until they took over, so we keep going round
+func (c *chatClient) failover() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.servers) < 2 {
+		return
+	}
+	from := c.servers[c.active].addr
+	c.active = (c.active + 1) % len(c.servers)
+	log.Printf("Client FAILOVER: %s did not answer, trying %s", from, c.servers[c.active].addr)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// lineScanner is how the input loop reads lines, bufio.Scanner is one
+type lineScanner interface {
+	Scan() bool
+	Text() string
+	Err() error
+}
+
+// newInput reads lines from stdin. On a terminal it reads key by key, so composing
+// tells whether the line is empty or not after every key but Enter. Otherwise stdin
+// is read line by line as usual. restore puts the terminal back the way it was
+func newInput(composing func(typing bool)) (input lineScanner, restore func()) {
+	restore, err := rawTerminal(int(os.Stdin.Fd()))
+	if err != nil {
+		return bufio.NewScanner(os.Stdin), func() {}
+	}
+	t := &terminalInput{in: bufio.NewReader(os.Stdin), out: os.Stdout, composing: composing}
+	//Log lines go above the line being typed instead of into it
+	log.SetOutput(t)
+	return t, func() {
+		log.SetOutput(os.Stderr)
+		restore()
+	}
+}
+
+// terminalInput is a small line editor for a terminal without line buffering or echo.
+// It knows backspace, Ctrl-U to clear the line and Ctrl-D to leave
+type terminalInput struct {
+	in        *bufio.Reader
+	out       io.Writer
+	composing func(typing bool)
+
+	mutex sync.Mutex // the line and the terminal are shared with the log
+	line  []byte
+	text  string
+	err   error
+}
+
+// Write prints a log line and the line being typed after it again
+func (t *terminalInput) Write(p []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.out.Write([]byte("\r\033[K"))
+	n, err := t.out.Write(p)
+	t.out.Write(t.line)
+	return n, err
+}
+
+func (t *terminalInput) Scan() bool {
+	for {
+		b, err := t.in.ReadByte()
+		if err != nil {
+			if err != io.EOF {
+				t.err = err
+			}
+			return false
+		}
+		if !t.key(b) {
+			continue
+		}
+		switch {
+		case b == '\r' || b == '\n':
+			return true
+		case b == 0x04:
+			return false
+		}
+		t.composing(t.typing())
+	}
+}
+
+// key applies one key to the line. It returns false if nothing changed
+func (t *terminalInput) key(b byte) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch {
+	case b == '\r' || b == '\n':
+		t.out.Write([]byte("\n"))
+		t.text = string(t.line)
+		t.line = t.line[:0]
+	case b == 0x04 && len(t.line) == 0: // Ctrl-D
+		t.out.Write([]byte("\n"))
+		t.line = t.line[:0]
+	case b == 0x7f || b == 0x08: // backspace
+		if len(t.line) == 0 {
+			return false
+		}
+		_, size := utf8.DecodeLastRune(t.line)
+		t.line = t.line[:len(t.line)-size]
+		t.out.Write([]byte("\b \b"))
+	case b == 0x15: // Ctrl-U
+		t.line = t.line[:0]
+		t.out.Write([]byte("\r\033[K"))
+	case b == 0x1b: // arrow keys and the like, not supported
+		t.skipEscape()
+		return false
+	case b < 0x20 && b != '\t':
+		return false
+	default:
+		t.line = append(t.line, b)
+		t.out.Write([]byte{b})
+	}
+	return true
+}
+
+func (t *terminalInput) typing() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.line) > 0
+}
+
+// skipEscape reads the rest of an escape sequence, e.g. ESC [ A for the up arrow
+func (t *terminalInput) skipEscape() {
+	b, err := t.in.ReadByte()
+	if err != nil || b != '[' {
+		return
+	}
+	for {
+		b, err := t.in.ReadByte()
+		if err != nil || (b >= 0x40 && b <= 0x7e) {
+			return
+		}
+	}
+}
+
+func (t *terminalInput) Text() string { return t.text }
+
+func (t *terminalInput) Err() error { return t.err }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// who lists everyone in the current room with their status
+func (c *chatClient) who() {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client WHO_ERROR: not in a room, use /join <room>")
+		return
+	}
+	response, err := c.server().ListParticipants(context.Background(), &proto.ListParticipantsRequest{Room: session.name})
+	if err != nil {
+		log.Printf("Client WHO_ERROR: %v", err)
+		return
+	}
+	for _, p := range response.Participants {
+		joined := "unknown"
+		if p.JoinedAtMs > 0 {
+			joined = time.UnixMilli(p.JoinedAtMs).Format(time.TimeOnly)
+		}
+		log.Printf("Client WHO: room=%s %s status=%s joined_logical_time=%d joined_at=%s",
+			session.name, p.ClientId, statusName(p.Status), p.JoinedAt, joined)
+	}
+}
+
+// setStatus tells the current room we are away, or back
+func (c *chatClient) setStatus(away bool) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client STATUS_ERROR: not in a room, use /join <room>")
+		return
+	}
+	req := &proto.SetStatusRequest{ClientId: c.id, Room: session.name, Status: proto.ParticipantStatus_ONLINE, Timestamp: c.lamport.Tick()}
+	if away {
+		req.Status = proto.ParticipantStatus_AWAY
+	}
+	ctx, _ := c.authContext(session)
+	if _, err := c.server().SetStatus(ctx, req); err != nil {
+		log.Printf("Client STATUS_ERROR: %v", err)
+	}
+}
+
+// statusName is online, away or idle
+func statusName(status proto.ParticipantStatus) string {
+	return strings.ToLower(status.String())
+}
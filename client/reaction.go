@@ -0,0 +1,57 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// react adds our reaction to a message in the current room, or takes it back
+func (c *chatClient) react(messageID, reaction string, remove bool) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client REACTION_ERROR: not in a room, use /join <room>")
+		return
+	}
+	ctx, _ := c.authContext(session)
+	req := &proto.ReactionRequest{
+		ClientId:  c.id,
+		Room:      session.name,
+		MessageId: messageID,
+		Reaction:  reaction,
+		Timestamp: c.lamport.Tick(),
+	}
+	var err error
+	if remove {
+		_, err = c.server().RemoveReaction(ctx, req)
+	} else {
+		_, err = c.server().AddReaction(ctx, req)
+	}
+	if err != nil {
+		log.Printf("Client REACTION_ERROR: %v", err)
+	}
+}
+
+// reactionLabel shows the reaction counts of a message, empty if it has none
+func reactionLabel(reactions map[string]int32) string {
+	if len(reactions) == 0 {
+		return ""
+	}
+	return " reactions=[" + formatReactions(reactions) + "]"
+}
+
+// formatReactions lists reaction counts in a stable order, e.g. "👍 2, 🎉 1"
+func formatReactions(reactions map[string]int32) string {
+	names := make([]string, 0, len(reactions))
+	for reaction := range reactions {
+		names = append(names, reaction)
+	}
+	sort.Strings(names)
+	counts := make([]string, len(names))
+	for i, reaction := range names {
+		counts[i] = fmt.Sprintf("%s %d", reaction, reactions[reaction])
+	}
+	return strings.Join(counts, ", ")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	receiptFlush   = 500 * time.Millisecond // how often receipts are sent, in one batch
+	receiptBatch   = 128                    // message IDs of each kind per batch, the server takes 256
+	receiptBacklog = 1024                   // receipts kept while disconnected, older ones are dropped
+)
+
+// received notes a message of someone else that was shown, to acknowledge it
+// as delivered now and as read once we look at the terminal
+func (c *chatClient) received(session *roomSession, broadcast *proto.BroadCast) {
+	if broadcast.ClientId == c.id || broadcast.MessageId == "" || broadcast.Deleted {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	session.delivered = backlog(append(session.delivered, broadcast.MessageId))
+	session.unread = backlog(append(session.unread, broadcast.MessageId))
+}
+
+// looked marks everything shown so far as read, we are at the keyboard
+func (c *chatClient) looked() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	session := c.room
+	if session == nil || len(session.unread) == 0 {
+		return
+	}
+	session.read = backlog(append(session.read, session.unread...))
+	session.unread = nil
+}
+
+// receiptLoop sends the collected receipts every receiptFlush until ctx is cancelled
+func (c *chatClient) receiptLoop(ctx context.Context, session *roomSession) {
+	ticker := time.NewTicker(receiptFlush)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.mutex.Lock()
+		if !session.connected || len(session.delivered)+len(session.read) == 0 {
+			c.mutex.Unlock()
+			continue
+		}
+		delivered, read := take(&session.delivered), take(&session.read)
+		c.mutex.Unlock()
+
+		authCtx, _ := c.authContext(session)
+		authCtx, cancel := context.WithTimeout(authCtx, ackTimeout)
+		_, err := c.server().Acknowledge(authCtx, &proto.ReceiptRequest{
+			ClientId:  c.id,
+			Room:      session.name,
+			Delivered: delivered,
+			Read:      read,
+		})
+		cancel()
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != codes.Unavailable {
+			log.Printf("Client RECEIPT_ERROR: %v", err)
+			continue
+		}
+		//Try again once we are back
+		c.mutex.Lock()
+		session.delivered = backlog(append(delivered, session.delivered...))
+		session.read = backlog(append(read, session.read...))
+		c.mutex.Unlock()
+	}
+}
+
+// take removes up to receiptBatch IDs from the front of a list
+func take(ids *[]string) []string {
+	n := min(len(*ids), receiptBatch)
+	batch := (*ids)[:n:n]
+	*ids = (*ids)[n:]
+	return batch
+}
+
+// backlog drops the oldest IDs beyond receiptBacklog
+func backlog(ids []string) []string {
+	if len(ids) > receiptBacklog {
+		return ids[len(ids)-receiptBacklog:]
+	}
+	return ids
+}
+
+// showReceipt prints how far one of our messages got
+func (c *chatClient) showReceipt(session *roomSession, broadcast *proto.BroadCast) {
+	log.Printf("Client RECEIPT: room=%s message_id=%s delivered to %d / read by %d",
+		session.name, broadcast.MessageId, broadcast.Delivered, broadcast.Read)
+}
+
+// receipts prints who got and who read a message
+func (c *chatClient) receipts(messageID string) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client RECEIPT_ERROR: not in a room, use /join <room>")
+		return
+	}
+	ctx, _ := c.authContext(session)
+	response, err := c.server().GetReceipts(ctx, &proto.GetReceiptsRequest{ClientId: c.id, Room: session.name, MessageId: messageID})
+	if err != nil {
+		log.Printf("Client RECEIPT_ERROR: %v", err)
+		return
+	}
+	log.Printf("Client RECEIPTS: room=%s message_id=%s delivered to %d [%s] / read by %d [%s]",
+		session.name, messageID, len(response.DeliveredTo), strings.Join(response.DeliveredTo, ", "),
+		len(response.ReadBy), strings.Join(response.ReadBy, ", "))
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ackTimeout is how long we wait for the server to answer an event on the Chat stream
+const ackTimeout = 10 * time.Second
+
+// eventStream is our end of a Chat stream. Events are numbered so the ACK
+// the server answers with can be handed to whoever sent the event
+type eventStream struct {
+	stream proto.ChitChat_ChatClient
+
+	mutex   sync.Mutex
+	seq     int64
+	waiting map[int64]chan *proto.BroadCast // seq -> who waits for its ACK
+	done    chan struct{}                   // closed when the stream ended
+}
+
+func newEventStream(stream proto.ChitChat_ChatClient) *eventStream {
+	return &eventStream{
+		stream:  stream,
+		waiting: make(map[int64]chan *proto.BroadCast),
+		done:    make(chan struct{}),
+	}
+}
+
+// send numbers an event and sends it without waiting for the ACK
+func (e *eventStream) send(event *proto.ClientEvent) error {
+	_, err := e.post(event, false)
+	return err
+}
+
+// request sends an event and waits for the servers ACK. A broken stream is
+// reported as Unavailable, like a failed unary call
+func (e *eventStream) request(event *proto.ClientEvent) (*proto.BroadCast, error) {
+	answer, err := e.post(event, true)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case ack := <-answer:
+		return ack, nil
+	case <-e.done:
+		//The ACK may have come in just before the stream closed, e.g. for a leave
+		select {
+		case ack := <-answer:
+			return ack, nil
+		default:
+		}
+		return nil, status.Error(codes.Unavailable, "chat stream closed")
+	case <-time.After(ackTimeout):
+		e.forget(event.Seq)
+		return nil, status.Errorf(codes.DeadlineExceeded, "no ACK for event %d within %s", event.Seq, ackTimeout)
+	}
+}
+
+// post sends the event, registering a channel for its ACK if wait is set
+func (e *eventStream) post(event *proto.ClientEvent, wait bool) (chan *proto.BroadCast, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.seq++
+	event.Seq = e.seq
+	var answer chan *proto.BroadCast
+	if wait {
+		answer = make(chan *proto.BroadCast, 1)
+		e.waiting[event.Seq] = answer
+	}
+	if err := e.stream.Send(event); err != nil {
+		delete(e.waiting, event.Seq)
+		return nil, status.Errorf(codes.Unavailable, "chat stream broken: %v", err)
+	}
+	return answer, nil
+}
+
+// acked hands an ACK to whoever waits for it, ACKs nobody waits for are dropped
+func (e *eventStream) acked(ack *proto.BroadCast) {
+	e.mutex.Lock()
+	answer := e.waiting[ack.AckSeq]
+	delete(e.waiting, ack.AckSeq)
+	e.mutex.Unlock()
+	if answer != nil {
+		answer <- ack
+	}
+}
+
+func (e *eventStream) forget(seq int64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.waiting, seq)
+}
+
+// receiveChat is receive for the Chat transport: it joins over a Chat stream and
+// runs it until it ends, meanwhile publish and leave go over the same stream
+func (c *chatClient) receiveChat(ctx context.Context, session *roomSession, subreq *proto.SubscribeRequest) (connected bool, err error) {
+	//Everything started for this stream stops with it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.server().Chat(ctx)
+	if err != nil {
+		return false, err
+	}
+	events := newEventStream(stream)
+	defer close(events.done)
+
+	if err := events.send(&proto.ClientEvent{Event: &proto.ClientEvent_Join{Join: subreq}}); err != nil {
+		return false, err
+	}
+	//The server names this device's session in the response header once we joined
+	header, err := stream.Header()
+	if err == nil && len(header.Get(sessionHeader)) == 0 {
+		//Turned away before the stream started (e.g. by a backup), the reason comes with its end
+		_, err = stream.Recv()
+	}
+	if err != nil {
+		return false, err
+	}
+	c.mutex.Lock()
+	session.events = events
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		if session.events == events {
+			session.events = nil
+		}
+		c.mutex.Unlock()
+	}()
+	c.attached(ctx, session, header)
+
+	for {
+		broadcast, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// The server closed the stream, after our leave or because it kicked us
+				return true, nil
+			}
+			return true, err
+		}
+		if broadcast.Type == proto.BroadCast_ACK {
+			events.acked(broadcast)
+			continue
+		}
+		c.consume(session, broadcast)
+	}
+}
+
+// publishRequest sends a message over the unary Publish RPC or the Chat stream
+func (c *chatClient) publishRequest(session *roomSession, req *proto.PublishRequest) (*proto.PublishResponse, error) {
+	if !c.overChat {
+		ctx, _ := c.authContext(session)
+		ctx, cancel := context.WithTimeout(ctx, ackTimeout)
+		defer cancel()
+		return c.server().Publish(ctx, req)
+	}
+
+	events := c.eventsOf(session)
+	if events == nil {
+		return nil, status.Error(codes.Unavailable, "not connected")
+	}
+	ack, err := events.request(&proto.ClientEvent{Event: &proto.ClientEvent_Publish{Publish: req}})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.PublishResponse{Ack: ack.Error == "", Error: ack.Error, ErrorCode: ack.ErrorCode, MessageId: ack.MessageId}, nil
+}
+
+// leaveRequest ends our session over the unary Leave RPC or the Chat stream
+func (c *chatClient) leaveRequest(session *roomSession, req *proto.LeaveRequest) error {
+	if !c.overChat {
+		ctx, sessionID := c.authContext(session)
+		req.SessionId = sessionID
+		_, err := c.server().Leave(ctx, req)
+		return err
+	}
+
+	events := c.eventsOf(session)
+	if events == nil {
+		return status.Error(codes.Unavailable, "not connected")
+	}
+	ack, err := events.request(&proto.ClientEvent{Event: &proto.ClientEvent_Leave{Leave: req}})
+	if err != nil {
+		return err
+	}
+	if ack.Error != "" {
+		return errors.New(ack.Error)
+	}
+	return nil
+}
+
+func (c *chatClient) eventsOf(session *roomSession) *eventStream {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return session.events
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "errors"
+
+// rawTerminal is not supported here, stdin is read line by line and no typing is sent
+func rawTerminal(fd int) (restore func(), err error) {
+	return nil, errors.New("reading key by key is not supported on this platform")
+}
@@ -0,0 +1,39 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// rawTerminal turns off line buffering and echo on the terminal at fd, so the client
+// sees every key. Ctrl-C and Ctrl-Z still work. It fails if fd is not a terminal
+func rawTerminal(fd int) (restore func(), err error) {
+	old, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	raw := *old
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	restore = func() { unix.IoctlSetTermios(fd, ioctlSetTermios, old) }
+	//Being interrupted or killed must not leave the terminal without echo
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-signals
+		restore()
+		signal.Reset()
+		syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+	}()
+	return restore, nil
+}
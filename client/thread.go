@@ -0,0 +1,61 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"fmt"
+	"log"
+)
+
+// reply answers a message in the current room. A private message is answered privately
+func (c *chatClient) reply(parentID, text string) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client REPLY_ERROR: not in a room, use /join <room>")
+		return
+	}
+	c.mutex.Lock()
+	recipient := session.privateWith[parentID]
+	c.mutex.Unlock()
+	c.publish(text, recipient, parentID)
+}
+
+// thread prints a message and every reply to it, oldest first
+func (c *chatClient) thread(messageID string) {
+	session := c.current()
+	if session == nil {
+		log.Printf("Client THREAD_ERROR: not in a room, use /join <room>")
+		return
+	}
+	ctx, _ := c.authContext(session)
+	response, err := c.server().GetThread(ctx, &proto.GetThreadRequest{
+		ClientId:  c.id,
+		Room:      session.name,
+		MessageId: messageID,
+	})
+	if err != nil {
+		log.Printf("Client THREAD_ERROR: %v", err)
+		return
+	}
+	message := response.Message
+	log.Printf("Client THREAD: message_id=%s from %s logical_time=%d replies=%d content=%q%s",
+		message.MessageId, message.ClientId, message.Timestamp, len(response.Replies), content(message), reactionLabel(message.Reactions))
+	for _, reply := range response.Replies {
+		log.Printf("Client THREAD_REPLY: message_id=%s from %s logical_time=%d content=%q%s",
+			reply.MessageId, reply.ClientId, reply.Timestamp, content(reply), reactionLabel(reply.Reactions))
+	}
+}
+
+// threadLabel counts a reply to the message it answers and says so, empty for other messages
+func (c *chatClient) threadLabel(session *roomSession, broadcast *proto.BroadCast) string {
+	if broadcast.ParentId == "" {
+		return ""
+	}
+	c.mutex.Lock()
+	session.replies[broadcast.ParentId]++
+	count := session.replies[broadcast.ParentId]
+	c.mutex.Unlock()
+	if count == 1 {
+		return fmt.Sprintf(" reply_to=%s (1 reply)", broadcast.ParentId)
+	}
+	return fmt.Sprintf(" reply_to=%s (%d replies)", broadcast.ParentId, count)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// transportCredentials picks plaintext when no TLS flag is set, otherwise TLS
+// verified against caFile (or the system roots). With a client certificate it also
+// returns the certificate CN, which the server uses as our participant ID
+func transportCredentials(certFile, keyFile, caFile string) (credentials.TransportCredentials, string, error) {
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return insecure.NewCredentials(), "", nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, "", fmt.Errorf("no certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	var commonName string
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, "", errors.New("-cert and -key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("load client certificate: %w", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, "", fmt.Errorf("parse client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+		commonName = leaf.Subject.CommonName
+	}
+	return credentials.NewTLS(config), commonName, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// typingRefresh is how often typing is repeated while we keep typing,
+	// the server stops showing it when it hears nothing for a few seconds
+	typingRefresh = 3 * time.Second
+	// typingIdle is how long an unsent line or message still counts as typing
+	// after the last key
+	typingIdle = 30 * time.Second
+)
+
+// composing is called on every key and after every line: typing is true while there
+// is an unsent line, or a message over several lines is not finished yet
+func (c *chatClient) composing(typing bool) {
+	c.looked()
+	c.mutex.Lock()
+	session := c.room
+	if session == nil || !session.connected {
+		c.mutex.Unlock()
+		return
+	}
+	wasTyping := !session.typingSent.IsZero()
+	send := typing != wasTyping
+	if typing {
+		session.lastKey = time.Now()
+		if send {
+			session.typingSent = time.Now()
+		}
+	} else {
+		session.typingSent = time.Time{}
+	}
+	c.mutex.Unlock()
+
+	if send {
+		go c.sendTyping(session, typing)
+	}
+}
+
+// typingLoop repeats that we are typing on a timer, also while nothing is entered for a
+// while, until the line is sent, cleared or left alone for typingIdle
+func (c *chatClient) typingLoop(ctx context.Context, session *roomSession) {
+	ticker := time.NewTicker(typingRefresh / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.mutex.Lock()
+		typing := !session.typingSent.IsZero()
+		idle := typing && time.Since(session.lastKey) >= typingIdle
+		send := session.connected && typing && (idle || time.Since(session.typingSent) >= typingRefresh)
+		if idle {
+			session.typingSent = time.Time{}
+		} else if send {
+			session.typingSent = time.Now()
+		}
+		c.mutex.Unlock()
+
+		if send {
+			c.sendTyping(session, !idle)
+		}
+	}
+}
+
+// sendTyping tells the server over the Typing RPC or the Chat stream, without waiting for an ACK there
+func (c *chatClient) sendTyping(session *roomSession, typing bool) {
+	req := &proto.TypingRequest{ClientId: c.id, Room: session.name, Typing: typing}
+	var err error
+	if c.overChat {
+		if events := c.eventsOf(session); events != nil {
+			err = events.send(&proto.ClientEvent{Event: &proto.ClientEvent_Typing{Typing: req}})
+		}
+	} else {
+		ctx, _ := c.authContext(session)
+		ctx, cancel := context.WithTimeout(ctx, ackTimeout)
+		defer cancel()
+		_, err = c.server().Typing(ctx, req)
+	}
+	//Typing is not worth a retry, the timer sends it again
+	if err != nil && status.Code(err) != codes.Unavailable {
+		log.Printf("Client TYPING_ERROR: %v", err)
+	}
+}
+
+// typingChanged updates who is typing in the room and shows the status line if it changed
+func (c *chatClient) typingChanged(session *roomSession, clientID string, typing bool) {
+	c.mutex.Lock()
+	if session.typing[clientID] == typing {
+		c.mutex.Unlock()
+		return
+	}
+	if typing {
+		session.typing[clientID] = true
+	} else {
+		delete(session.typing, clientID)
+	}
+	typists := make([]string, 0, len(session.typing))
+	for id := range session.typing {
+		typists = append(typists, id)
+	}
+	c.mutex.Unlock()
+
+	sort.Strings(typists)
+	switch len(typists) {
+	case 0:
+		log.Printf("Client STATUS: room=%s nobody is typing", session.name)
+	case 1:
+		log.Printf("Client STATUS: room=%s %s is typing…", session.name, typists[0])
+	default:
+		last := len(typists) - 1
+		log.Printf("Client STATUS: room=%s %s and %s are typing…", session.name, strings.Join(typists[:last], ", "), typists[last])
+	}
+}
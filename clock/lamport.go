@@ -0,0 +1,52 @@
+// Package clock holds the logical clocks shared by the ChitChat client and server
+package clock
+
+import "sync"
+
+// Lamport is a thread safe Lamport logical clock
+type Lamport struct {
+	mutex sync.Mutex
+	time  int64
+}
+
+// NewLamport returns a clock starting at the given time, e.g. restored from history
+func NewLamport(start int64) *Lamport {
+	return &Lamport{time: start}
+}
+
+// Tick advances the clock for a local event (like sending a message) and returns the new time
+func (c *Lamport) Tick() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.time++
+	return c.time
+}
+
+// Merge handles a received timestamp: time = max(local, remote) + 1
+func (c *Lamport) Merge(remote int64) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if remote > c.time {
+		c.time = remote
+	}
+	c.time++
+	return c.time
+}
+
+// Advance moves the clock forward to at least the given time without ticking,
+// e.g. to follow the clock of another replica
+func (c *Lamport) Advance(to int64) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if to > c.time {
+		c.time = to
+	}
+	return c.time
+}
+
+// Now returns the current time without advancing the clock
+func (c *Lamport) Now() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.time
+}
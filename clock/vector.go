@@ -0,0 +1,97 @@
+package clock
+
+import "sync"
+
+// Ordering is how two vector timestamps relate to each other
+type Ordering int
+
+const (
+	Equal      Ordering = iota
+	Before              // a happened before b
+	After               // a happened after b
+	Concurrent          // neither happened before the other
+)
+
+func (o Ordering) String() string {
+	switch o {
+	case Equal:
+		return "equal"
+	case Before:
+		return "before"
+	case After:
+		return "after"
+	}
+	return "concurrent"
+}
+
+// Vector is a thread safe vector clock: participant ID -> number of messages sent by them
+type Vector struct {
+	mutex  sync.Mutex
+	counts map[string]int64
+}
+
+func NewVector() *Vector {
+	return &Vector{counts: make(map[string]int64)}
+}
+
+// Tick counts a local send by id and returns a copy of the clock to attach to the message
+func (v *Vector) Tick(id string) map[string]int64 {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.counts[id]++
+	return v.snapshot()
+}
+
+// Merge takes the entrywise max with a received vector
+func (v *Vector) Merge(remote map[string]int64) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for id, count := range remote {
+		if count > v.counts[id] {
+			v.counts[id] = count
+		}
+	}
+}
+
+// Snapshot returns a copy of the clock that is safe to hand to the proto messages
+func (v *Vector) Snapshot() map[string]int64 {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.snapshot()
+}
+
+func (v *Vector) snapshot() map[string]int64 {
+	copied := make(map[string]int64, len(v.counts))
+	for id, count := range v.counts {
+		copied[id] = count
+	}
+	return copied
+}
+
+// Compare tells whether a happened before, after or concurrently with b.
+// Missing entries count as 0
+func Compare(a, b map[string]int64) Ordering {
+	less, greater := false, false
+	for id, count := range a {
+		if count < b[id] {
+			less = true
+		} else if count > b[id] {
+			greater = true
+		}
+	}
+	for id, count := range b {
+		if _, ok := a[id]; !ok && count > 0 {
+			less = true
+		}
+	}
+
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	}
+	return Equal
+}
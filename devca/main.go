@@ -0,0 +1,133 @@
+// devca generates a self-signed CA with a server certificate and client certificates
+// for running Chit Chat with TLS or mutual TLS locally. Not meant for production
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	outDir  = flag.String("out", "certs", "Directory the PEM files are written to")
+	hosts   = flag.String("hosts", "localhost,127.0.0.1", "Comma separated DNS names and IPs for the server certificate")
+	clients = flag.String("clients", "", "Comma separated participant IDs to create client certificates for (used as the CN)")
+	servers = flag.String("servers", "", "Comma separated cluster node IDs or federation server names to create server certificates for (used as the CN)")
+	valid   = flag.Duration("valid", 30*24*time.Hour, "How long the certificates are valid")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatalf("devca ERROR: %v", err)
+	}
+
+	//The CA signs everything else
+	caKey, caCert := mustCreate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Chit Chat dev CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}, nil, nil, "ca")
+
+	mustCreate(serverTemplate("chitchat-server"), caCert, caKey, "server")
+	//Servers that have to tell each other apart get one each, named after them
+	for _, name := range splitList(*servers) {
+		mustCreate(serverTemplate(name), caCert, caKey, name)
+	}
+
+	//The CN of a client certificate becomes the participant ID under mutual TLS
+	for _, id := range splitList(*clients) {
+		mustCreate(&x509.Certificate{
+			Subject:     pkix.Name{CommonName: id},
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, caCert, caKey, id)
+	}
+
+	log.Printf("devca DONE: wrote certificates to %s", *outDir)
+}
+
+// serverTemplate is a server certificate for the -hosts. Servers dial each other with it,
+// so it is a client certificate too
+func serverTemplate(commonName string) *x509.Certificate {
+	server := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, host := range splitList(*hosts) {
+		if ip := net.ParseIP(host); ip != nil {
+			server.IPAddresses = append(server.IPAddresses, ip)
+		} else {
+			server.DNSNames = append(server.DNSNames, host)
+		}
+	}
+	return server
+}
+
+// mustCreate fills in the common fields, signs the template with the parent
+// (self-signed if parent is nil) and writes NAME.pem and NAME-key.pem
+func mustCreate(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatalf("devca ERROR: generate key for %s: %v", name, err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		log.Fatalf("devca ERROR: serial for %s: %v", name, err)
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(*valid)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		log.Fatalf("devca ERROR: create certificate for %s: %v", name, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		log.Fatalf("devca ERROR: parse certificate for %s: %v", name, err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		log.Fatalf("devca ERROR: marshal key for %s: %v", name, err)
+	}
+	writePEM(name+".pem", "CERTIFICATE", der, 0644)
+	writePEM(name+"-key.pem", "EC PRIVATE KEY", keyDER, 0600)
+	return key, cert
+}
+
+func writePEM(file, blockType string, der []byte, mode os.FileMode) {
+	path := filepath.Join(*outDir, file)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, mode); err != nil {
+		log.Fatalf("devca ERROR: write %s: %v", path, err)
+	}
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -21,25 +21,179 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// PublishError says which step of the servers validation rejected a message
+type PublishError int32
+
+const (
+	PublishError_NO_ERROR            PublishError = 0
+	PublishError_INVALID_UTF8        PublishError = 1
+	PublishError_EMPTY_MESSAGE       PublishError = 2 // nothing left once control and bidi characters are stripped
+	PublishError_TOO_MANY_CHARACTERS PublishError = 3
+	PublishError_TOO_MANY_BYTES      PublishError = 4
+	PublishError_BLOCKED_WORD        PublishError = 5
+)
+
+// Enum value maps for PublishError.
+var (
+	PublishError_name = map[int32]string{
+		0: "NO_ERROR",
+		1: "INVALID_UTF8",
+		2: "EMPTY_MESSAGE",
+		3: "TOO_MANY_CHARACTERS",
+		4: "TOO_MANY_BYTES",
+		5: "BLOCKED_WORD",
+	}
+	PublishError_value = map[string]int32{
+		"NO_ERROR":            0,
+		"INVALID_UTF8":        1,
+		"EMPTY_MESSAGE":       2,
+		"TOO_MANY_CHARACTERS": 3,
+		"TOO_MANY_BYTES":      4,
+		"BLOCKED_WORD":        5,
+	}
+)
+
+func (x PublishError) Enum() *PublishError {
+	p := new(PublishError)
+	*p = x
+	return p
+}
+
+func (x PublishError) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PublishError) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_proto_enumTypes[0].Descriptor()
+}
+
+func (PublishError) Type() protoreflect.EnumType {
+	return &file_proto_proto_enumTypes[0]
+}
+
+func (x PublishError) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PublishError.Descriptor instead.
+func (PublishError) EnumDescriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{0}
+}
+
+// ParticipantStatus is how present someone is. AWAY is set by the participant,
+// IDLE is worked out by the server when they did nothing for a while
+type ParticipantStatus int32
+
+const (
+	ParticipantStatus_ONLINE ParticipantStatus = 0
+	ParticipantStatus_AWAY   ParticipantStatus = 1
+	ParticipantStatus_IDLE   ParticipantStatus = 2
+)
+
+// Enum value maps for ParticipantStatus.
+var (
+	ParticipantStatus_name = map[int32]string{
+		0: "ONLINE",
+		1: "AWAY",
+		2: "IDLE",
+	}
+	ParticipantStatus_value = map[string]int32{
+		"ONLINE": 0,
+		"AWAY":   1,
+		"IDLE":   2,
+	}
+)
+
+func (x ParticipantStatus) Enum() *ParticipantStatus {
+	p := new(ParticipantStatus)
+	*p = x
+	return p
+}
+
+func (x ParticipantStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ParticipantStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_proto_enumTypes[1].Descriptor()
+}
+
+func (ParticipantStatus) Type() protoreflect.EnumType {
+	return &file_proto_proto_enumTypes[1]
+}
+
+func (x ParticipantStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ParticipantStatus.Descriptor instead.
+func (ParticipantStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{1}
+}
+
+// this enum Type code makes it easy and dynamic to specify what type of
+// message that should be broadcasted to all the other clients
 type BroadCast_Type int32
 
 const (
-	BroadCast_CHAT  BroadCast_Type = 0
-	BroadCast_JOIN  BroadCast_Type = 1
-	BroadCast_LEAVE BroadCast_Type = 2
+	BroadCast_CHAT            BroadCast_Type = 0
+	BroadCast_JOIN            BroadCast_Type = 1
+	BroadCast_LEAVE           BroadCast_Type = 2
+	BroadCast_DIRECT          BroadCast_Type = 3 // private message, only sent to the recipient and echoed to the sender
+	BroadCast_SERVER_SHUTDOWN BroadCast_Type = 4 // the server is going away, message holds the reason
+	BroadCast_HEARTBEAT       BroadCast_Type = 5 // sent every few seconds so both sides notice a dead stream, never persisted
+	BroadCast_ACK             BroadCast_Type = 6 // Chat only: answers the ClientEvent with seq ack_seq, error is set if it was rejected
+	BroadCast_EDIT            BroadCast_Type = 7 // message_id names the edited message, message holds its new text
+	BroadCast_DELETE          BroadCast_Type = 8 // message_id names the deleted message
+	BroadCast_REACTION        BroadCast_Type = 9 // client_id added or removed reaction on message_id, reactions holds the new counts
+	// client_id started or stopped typing. Like heartbeats they are never persisted and do
+	// not advance the clocks, timestamp is the rooms current time
+	BroadCast_TYPING_START BroadCast_Type = 10
+	BroadCast_TYPING_STOP  BroadCast_Type = 11
+	BroadCast_PRESENCE     BroadCast_Type = 12 // client_id set their status in the room to status
+	// only to the author of message_id: how many got and read it so far. Never persisted
+	// and does not advance the clocks
+	BroadCast_RECEIPT BroadCast_Type = 13
+	// the room was created. Only kept in the history, so a room survives a restart
+	// before anything happened in it, and never sent to clients
+	BroadCast_ROOM_CREATED BroadCast_Type = 14
 )
 
 // Enum value maps for BroadCast_Type.
 var (
 	BroadCast_Type_name = map[int32]string{
-		0: "CHAT",
-		1: "JOIN",
-		2: "LEAVE",
+		0:  "CHAT",
+		1:  "JOIN",
+		2:  "LEAVE",
+		3:  "DIRECT",
+		4:  "SERVER_SHUTDOWN",
+		5:  "HEARTBEAT",
+		6:  "ACK",
+		7:  "EDIT",
+		8:  "DELETE",
+		9:  "REACTION",
+		10: "TYPING_START",
+		11: "TYPING_STOP",
+		12: "PRESENCE",
+		13: "RECEIPT",
+		14: "ROOM_CREATED",
 	}
 	BroadCast_Type_value = map[string]int32{
-		"CHAT":  0,
-		"JOIN":  1,
-		"LEAVE": 2,
+		"CHAT":            0,
+		"JOIN":            1,
+		"LEAVE":           2,
+		"DIRECT":          3,
+		"SERVER_SHUTDOWN": 4,
+		"HEARTBEAT":       5,
+		"ACK":             6,
+		"EDIT":            7,
+		"DELETE":          8,
+		"REACTION":        9,
+		"TYPING_START":    10,
+		"TYPING_STOP":     11,
+		"PRESENCE":        12,
+		"RECEIPT":         13,
+		"ROOM_CREATED":    14,
 	}
 )
 
@@ -54,11 +208,11 @@ func (x BroadCast_Type) String() string {
 }
 
 func (BroadCast_Type) Descriptor() protoreflect.EnumDescriptor {
-	return file_proto_proto_enumTypes[0].Descriptor()
+	return file_proto_proto_enumTypes[2].Descriptor()
 }
 
 func (BroadCast_Type) Type() protoreflect.EnumType {
-	return &file_proto_proto_enumTypes[0]
+	return &file_proto_proto_enumTypes[2]
 }
 
 func (x BroadCast_Type) Number() protoreflect.EnumNumber {
@@ -70,12 +224,90 @@ func (BroadCast_Type) EnumDescriptor() ([]byte, []int) {
 	return file_proto_proto_rawDescGZIP(), []int{0, 0}
 }
 
+type RaftEntry_Kind int32
+
+const (
+	RaftEntry_COMMAND RaftEntry_Kind = 0 // data is a command for the state machine
+	RaftEntry_CONFIG  RaftEntry_Kind = 1 // data is a RaftConfig, the cluster from here on
+	RaftEntry_NOOP    RaftEntry_Kind = 2 // a new leader commits one of these to learn what is committed
+)
+
+// Enum value maps for RaftEntry_Kind.
+var (
+	RaftEntry_Kind_name = map[int32]string{
+		0: "COMMAND",
+		1: "CONFIG",
+		2: "NOOP",
+	}
+	RaftEntry_Kind_value = map[string]int32{
+		"COMMAND": 0,
+		"CONFIG":  1,
+		"NOOP":    2,
+	}
+)
+
+func (x RaftEntry_Kind) Enum() *RaftEntry_Kind {
+	p := new(RaftEntry_Kind)
+	*p = x
+	return p
+}
+
+func (x RaftEntry_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RaftEntry_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_proto_enumTypes[3].Descriptor()
+}
+
+func (RaftEntry_Kind) Type() protoreflect.EnumType {
+	return &file_proto_proto_enumTypes[3]
+}
+
+func (x RaftEntry_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RaftEntry_Kind.Descriptor instead.
+func (RaftEntry_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{41, 0}
+}
+
 type BroadCast struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Type          BroadCast_Type         `protobuf:"varint,1,opt,name=type,proto3,enum=BroadCast_Type" json:"type,omitempty"`
-	ClientId      string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
-	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Lamport Clock
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Type      BroadCast_Type         `protobuf:"varint,1,opt,name=type,proto3,enum=BroadCast_Type" json:"type,omitempty"` // from enum Type
+	ClientId  string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Message   string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Lamport Clock
+	// Vector Clock, only filled when the server runs in vector mode.
+	// For CHAT it is the senders clock, for the other types the servers merged view
+	Vector           map[string]int64 `protobuf:"bytes,5,rep,name=vector,proto3" json:"vector,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Room             string           `protobuf:"bytes,6,opt,name=room,proto3" json:"room,omitempty"`                                                    // room the broadcast belongs to
+	Recipient        string           `protobuf:"bytes,7,opt,name=recipient,proto3" json:"recipient,omitempty"`                                          // only set for DIRECT
+	ReconnectAfterMs int64            `protobuf:"varint,8,opt,name=reconnect_after_ms,json=reconnectAfterMs,proto3" json:"reconnect_after_ms,omitempty"` // SERVER_SHUTDOWN: how long clients should wait before reconnecting
+	AckSeq           int64            `protobuf:"varint,9,opt,name=ack_seq,json=ackSeq,proto3" json:"ack_seq,omitempty"`                                 // ACK: seq of the ClientEvent it answers
+	Error            string           `protobuf:"bytes,10,opt,name=error,proto3" json:"error,omitempty"`                                                 // ACK: why the event was rejected, empty if it was accepted
+	// Federation: the server the event happened on and its ID there, set on every
+	// event of a federated server. Events from other servers have client_id user@server
+	Origin    string `protobuf:"bytes,11,opt,name=origin,proto3" json:"origin,omitempty"`
+	EventId   int64  `protobuf:"varint,12,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	MessageId string `protobuf:"bytes,13,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // CHAT and DIRECT: chosen by the sender, ACK: the ID of the published message
+	// History replay folds later edits and deletes into the message itself
+	Edited   bool   `protobuf:"varint,14,opt,name=edited,proto3" json:"edited,omitempty"`                    // message holds the latest text
+	Deleted  bool   `protobuf:"varint,15,opt,name=deleted,proto3" json:"deleted,omitempty"`                  // message is empty, show a tombstone
+	ParentId string `protobuf:"bytes,16,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"` // CHAT and DIRECT: the message this one replies to, empty if it starts a thread
+	Reaction string `protobuf:"bytes,17,opt,name=reaction,proto3" json:"reaction,omitempty"`                 // REACTION: the reaction that was added or removed
+	Removed  bool   `protobuf:"varint,18,opt,name=removed,proto3" json:"removed,omitempty"`                  // REACTION: it was taken back
+	// REACTION: every reaction on the message and how many participants gave it.
+	// History replay folds them into the message itself
+	Reactions map[string]int32  `protobuf:"bytes,19,rep,name=reactions,proto3" json:"reactions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Status    ParticipantStatus `protobuf:"varint,20,opt,name=status,proto3,enum=ParticipantStatus" json:"status,omitempty"`                   // PRESENCE: the new status
+	Delivered int32             `protobuf:"varint,21,opt,name=delivered,proto3" json:"delivered,omitempty"`                                    // RECEIPT: participants who got the message
+	Read      int32             `protobuf:"varint,22,opt,name=read,proto3" json:"read,omitempty"`                                              // RECEIPT: participants who read it, they count as delivered too
+	ErrorCode PublishError      `protobuf:"varint,23,opt,name=error_code,json=errorCode,proto3,enum=PublishError" json:"error_code,omitempty"` // ACK: which validation step rejected a publish
+	// Lamport time of the last broadcast before this one that went to the whole room.
+	// Clients holding it back only wait when that one has not arrived yet
+	Previous      int64 `protobuf:"varint,24,opt,name=previous,proto3" json:"previous,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -138,11 +370,155 @@ func (x *BroadCast) GetTimestamp() int64 {
 	return 0
 }
 
+func (x *BroadCast) GetVector() map[string]int64 {
+	if x != nil {
+		return x.Vector
+	}
+	return nil
+}
+
+func (x *BroadCast) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *BroadCast) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *BroadCast) GetReconnectAfterMs() int64 {
+	if x != nil {
+		return x.ReconnectAfterMs
+	}
+	return 0
+}
+
+func (x *BroadCast) GetAckSeq() int64 {
+	if x != nil {
+		return x.AckSeq
+	}
+	return 0
+}
+
+func (x *BroadCast) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BroadCast) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *BroadCast) GetEventId() int64 {
+	if x != nil {
+		return x.EventId
+	}
+	return 0
+}
+
+func (x *BroadCast) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *BroadCast) GetEdited() bool {
+	if x != nil {
+		return x.Edited
+	}
+	return false
+}
+
+func (x *BroadCast) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+func (x *BroadCast) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *BroadCast) GetReaction() string {
+	if x != nil {
+		return x.Reaction
+	}
+	return ""
+}
+
+func (x *BroadCast) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+func (x *BroadCast) GetReactions() map[string]int32 {
+	if x != nil {
+		return x.Reactions
+	}
+	return nil
+}
+
+func (x *BroadCast) GetStatus() ParticipantStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ParticipantStatus_ONLINE
+}
+
+func (x *BroadCast) GetDelivered() int32 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *BroadCast) GetRead() int32 {
+	if x != nil {
+		return x.Read
+	}
+	return 0
+}
+
+func (x *BroadCast) GetErrorCode() PublishError {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return PublishError_NO_ERROR
+}
+
+func (x *BroadCast) GetPrevious() int64 {
+	if x != nil {
+		return x.Previous
+	}
+	return 0
+}
+
 type SubscribeRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// history replay before live streaming starts, last_n wins if both are set
+	SinceTimestamp int64  `protobuf:"varint,2,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"` // replay every broadcast with a timestamp after this one
+	LastN          int32  `protobuf:"varint,3,opt,name=last_n,json=lastN,proto3" json:"last_n,omitempty"`                            // replay the last N broadcasts
+	Room           string `protobuf:"bytes,4,opt,name=room,proto3" json:"room,omitempty"`                                            // room to join, empty means the default room
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *SubscribeRequest) Reset() {
@@ -182,10 +558,39 @@ func (x *SubscribeRequest) GetId() string {
 	return ""
 }
 
+func (x *SubscribeRequest) GetSinceTimestamp() int64 {
+	if x != nil {
+		return x.SinceTimestamp
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetLastN() int32 {
+	if x != nil {
+		return x.LastN
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
 type PublishRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
-	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ClientId  string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Text      string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Timestamp int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                                     // senders Lamport Clock, merged by the server
+	Vector    map[string]int64       `protobuf:"bytes,4,rep,name=vector,proto3" json:"vector,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // senders Vector Clock, used in vector mode
+	Room      string                 `protobuf:"bytes,5,opt,name=room,proto3" json:"room,omitempty"`                                                                                // empty means the default room
+	Recipient string                 `protobuf:"bytes,6,opt,name=recipient,proto3" json:"recipient,omitempty"`                                                                      // set to send a DIRECT message to one participant in the room
+	// chosen by the client and kept when it retries, the server only posts the message once.
+	// Empty lets the server pick one
+	MessageId     string `protobuf:"bytes,7,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	ParentId      string `protobuf:"bytes,8,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"` // message_id of the message this one replies to, empty if it is no reply
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -234,10 +639,54 @@ func (x *PublishRequest) GetText() string {
 	return ""
 }
 
+func (x *PublishRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *PublishRequest) GetVector() map[string]int64 {
+	if x != nil {
+		return x.Vector
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
 type PublishResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
 	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`                    // the ID the message was broadcast with, also for a retry
+	ErrorCode     PublishError           `protobuf:"varint,4,opt,name=error_code,json=errorCode,proto3,enum=PublishError" json:"error_code,omitempty"` // set with ack false when validation rejected the message
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -286,9 +735,26 @@ func (x *PublishResponse) GetError() string {
 	return ""
 }
 
+func (x *PublishResponse) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *PublishResponse) GetErrorCode() PublishError {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return PublishError_NO_ERROR
+}
+
 type LeaveRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                 // senders Lamport Clock, merged by the server
+	Room          string                 `protobuf:"bytes,3,opt,name=room,proto3" json:"room,omitempty"`                            // empty means the default room
+	SessionId     string                 `protobuf:"bytes,4,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // only end this session (device), empty ends all of the clients sessions
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -330,29 +796,2788 @@ func (x *LeaveRequest) GetClientId() string {
 	return ""
 }
 
-type LeaveResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *LeaveResponse) Reset() {
-	*x = LeaveResponse{}
-	mi := &file_proto_proto_msgTypes[5]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *LeaveRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *LeaveRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type TypingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`      // empty means the default room
+	Typing        bool                   `protobuf:"varint,3,opt,name=typing,proto3" json:"typing,omitempty"` // false once the participant stopped typing
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TypingRequest) Reset() {
+	*x = TypingRequest{}
+	mi := &file_proto_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TypingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TypingRequest) ProtoMessage() {}
+
+func (x *TypingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TypingRequest.ProtoReflect.Descriptor instead.
+func (*TypingRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TypingRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *TypingRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *TypingRequest) GetTyping() bool {
+	if x != nil {
+		return x.Typing
+	}
+	return false
+}
+
+type TypingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TypingResponse) Reset() {
+	*x = TypingResponse{}
+	mi := &file_proto_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TypingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TypingResponse) ProtoMessage() {}
+
+func (x *TypingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TypingResponse.ProtoReflect.Descriptor instead.
+func (*TypingResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TypingResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *TypingResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ClientEvent is everything a client sends over a Chat stream. The first one must be a join,
+// every event is answered with an ACK broadcast carrying its seq
+type ClientEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   int64                  `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"` // chosen by the client, echoed in the ACK
+	// Types that are valid to be assigned to Event:
+	//
+	//	*ClientEvent_Join
+	//	*ClientEvent_Publish
+	//	*ClientEvent_Typing
+	//	*ClientEvent_Leave
+	Event         isClientEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientEvent) Reset() {
+	*x = ClientEvent{}
+	mi := &file_proto_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientEvent) ProtoMessage() {}
+
+func (x *ClientEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientEvent.ProtoReflect.Descriptor instead.
+func (*ClientEvent) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ClientEvent) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ClientEvent) GetEvent() isClientEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ClientEvent) GetJoin() *SubscribeRequest {
+	if x != nil {
+		if x, ok := x.Event.(*ClientEvent_Join); ok {
+			return x.Join
+		}
+	}
+	return nil
+}
+
+func (x *ClientEvent) GetPublish() *PublishRequest {
+	if x != nil {
+		if x, ok := x.Event.(*ClientEvent_Publish); ok {
+			return x.Publish
+		}
+	}
+	return nil
+}
+
+func (x *ClientEvent) GetTyping() *TypingRequest {
+	if x != nil {
+		if x, ok := x.Event.(*ClientEvent_Typing); ok {
+			return x.Typing
+		}
+	}
+	return nil
+}
+
+func (x *ClientEvent) GetLeave() *LeaveRequest {
+	if x != nil {
+		if x, ok := x.Event.(*ClientEvent_Leave); ok {
+			return x.Leave
+		}
+	}
+	return nil
+}
+
+type isClientEvent_Event interface {
+	isClientEvent_Event()
+}
+
+type ClientEvent_Join struct {
+	Join *SubscribeRequest `protobuf:"bytes,2,opt,name=join,proto3,oneof"`
+}
+
+type ClientEvent_Publish struct {
+	Publish *PublishRequest `protobuf:"bytes,3,opt,name=publish,proto3,oneof"`
+}
+
+type ClientEvent_Typing struct {
+	Typing *TypingRequest `protobuf:"bytes,4,opt,name=typing,proto3,oneof"`
+}
+
+type ClientEvent_Leave struct {
+	Leave *LeaveRequest `protobuf:"bytes,5,opt,name=leave,proto3,oneof"`
+}
+
+func (*ClientEvent_Join) isClientEvent_Event() {}
+
+func (*ClientEvent_Publish) isClientEvent_Event() {}
+
+func (*ClientEvent_Typing) isClientEvent_Event() {}
+
+func (*ClientEvent_Leave) isClientEvent_Event() {}
+
+// EditRequest replaces the text of an earlier message, only its author or an admin may
+type EditRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"` // empty means the default room
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // senders Lamport Clock, merged by the server
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EditRequest) Reset() {
+	*x = EditRequest{}
+	mi := &file_proto_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EditRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EditRequest) ProtoMessage() {}
+
+func (x *EditRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EditRequest.ProtoReflect.Descriptor instead.
+func (*EditRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *EditRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *EditRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *EditRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *EditRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *EditRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type EditResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	ErrorCode     PublishError           `protobuf:"varint,3,opt,name=error_code,json=errorCode,proto3,enum=PublishError" json:"error_code,omitempty"` // the new text is validated like a published message
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EditResponse) Reset() {
+	*x = EditResponse{}
+	mi := &file_proto_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EditResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EditResponse) ProtoMessage() {}
+
+func (x *EditResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EditResponse.ProtoReflect.Descriptor instead.
+func (*EditResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *EditResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *EditResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *EditResponse) GetErrorCode() PublishError {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return PublishError_NO_ERROR
+}
+
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"` // empty means the default room
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // senders Lamport Clock, merged by the server
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_proto_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *DeleteRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *DeleteRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *DeleteRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_proto_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *DeleteResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// GetThreadRequest asks for a message and every reply to it, like Publish it needs the session token
+type GetThreadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"` // empty means the default room
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetThreadRequest) Reset() {
+	*x = GetThreadRequest{}
+	mi := &file_proto_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetThreadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetThreadRequest) ProtoMessage() {}
+
+func (x *GetThreadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetThreadRequest.ProtoReflect.Descriptor instead.
+func (*GetThreadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetThreadRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *GetThreadRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *GetThreadRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+type GetThreadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *BroadCast             `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"` // the message itself, with edits and deletes applied
+	Replies       []*BroadCast           `protobuf:"bytes,2,rep,name=replies,proto3" json:"replies,omitempty"` // in Lamport order, with edits and deletes applied
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetThreadResponse) Reset() {
+	*x = GetThreadResponse{}
+	mi := &file_proto_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetThreadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetThreadResponse) ProtoMessage() {}
+
+func (x *GetThreadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetThreadResponse.ProtoReflect.Descriptor instead.
+func (*GetThreadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetThreadResponse) GetMessage() *BroadCast {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *GetThreadResponse) GetReplies() []*BroadCast {
+	if x != nil {
+		return x.Replies
+	}
+	return nil
+}
+
+// ReactionRequest adds or removes one reaction of a participant to a message,
+// like Publish it needs the session token
+type ReactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"` // empty means the default room
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Reaction      string                 `protobuf:"bytes,4,opt,name=reaction,proto3" json:"reaction,omitempty"`    // any short string, e.g. an emoji
+	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // senders Lamport Clock, merged by the server
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReactionRequest) Reset() {
+	*x = ReactionRequest{}
+	mi := &file_proto_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReactionRequest) ProtoMessage() {}
+
+func (x *ReactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReactionRequest.ProtoReflect.Descriptor instead.
+func (*ReactionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ReactionRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *ReactionRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *ReactionRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *ReactionRequest) GetReaction() string {
+	if x != nil {
+		return x.Reaction
+	}
+	return ""
+}
+
+func (x *ReactionRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type ReactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Reactions     map[string]int32       `protobuf:"bytes,3,rep,name=reactions,proto3" json:"reactions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // the counts on the message afterwards
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReactionResponse) Reset() {
+	*x = ReactionResponse{}
+	mi := &file_proto_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReactionResponse) ProtoMessage() {}
+
+func (x *ReactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReactionResponse.ProtoReflect.Descriptor instead.
+func (*ReactionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ReactionResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *ReactionResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ReactionResponse) GetReactions() map[string]int32 {
+	if x != nil {
+		return x.Reactions
+	}
+	return nil
+}
+
+type CreateRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRoomRequest) Reset() {
+	*x = CreateRoomRequest{}
+	mi := &file_proto_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRoomRequest) ProtoMessage() {}
+
+func (x *CreateRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRoomRequest.ProtoReflect.Descriptor instead.
+func (*CreateRoomRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CreateRoomRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type CreateRoomResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRoomResponse) Reset() {
+	*x = CreateRoomResponse{}
+	mi := &file_proto_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRoomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRoomResponse) ProtoMessage() {}
+
+func (x *CreateRoomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRoomResponse.ProtoReflect.Descriptor instead.
+func (*CreateRoomResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CreateRoomResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *CreateRoomResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListRoomsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoomsRequest) Reset() {
+	*x = ListRoomsRequest{}
+	mi := &file_proto_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoomsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoomsRequest) ProtoMessage() {}
+
+func (x *ListRoomsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoomsRequest.ProtoReflect.Descriptor instead.
+func (*ListRoomsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{18}
+}
+
+type RoomInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Members       int32                  `protobuf:"varint,2,opt,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoomInfo) Reset() {
+	*x = RoomInfo{}
+	mi := &file_proto_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomInfo) ProtoMessage() {}
+
+func (x *RoomInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomInfo.ProtoReflect.Descriptor instead.
+func (*RoomInfo) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RoomInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RoomInfo) GetMembers() int32 {
+	if x != nil {
+		return x.Members
+	}
+	return 0
+}
+
+type ListRoomsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rooms         []*RoomInfo            `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoomsResponse) Reset() {
+	*x = ListRoomsResponse{}
+	mi := &file_proto_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoomsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoomsResponse) ProtoMessage() {}
+
+func (x *ListRoomsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoomsResponse.ProtoReflect.Descriptor instead.
+func (*ListRoomsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ListRoomsResponse) GetRooms() []*RoomInfo {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+// ReceiptRequest acknowledges messages of others, in batches. Like Publish it needs the session token
+type ReceiptRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`           // empty means the default room
+	Delivered     []string               `protobuf:"bytes,3,rep,name=delivered,proto3" json:"delivered,omitempty"` // message IDs that reached the client
+	Read          []string               `protobuf:"bytes,4,rep,name=read,proto3" json:"read,omitempty"`           // message IDs the participant has seen
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiptRequest) Reset() {
+	*x = ReceiptRequest{}
+	mi := &file_proto_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiptRequest) ProtoMessage() {}
+
+func (x *ReceiptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiptRequest.ProtoReflect.Descriptor instead.
+func (*ReceiptRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReceiptRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *ReceiptRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *ReceiptRequest) GetDelivered() []string {
+	if x != nil {
+		return x.Delivered
+	}
+	return nil
+}
+
+func (x *ReceiptRequest) GetRead() []string {
+	if x != nil {
+		return x.Read
+	}
+	return nil
+}
+
+type ReceiptResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiptResponse) Reset() {
+	*x = ReceiptResponse{}
+	mi := &file_proto_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiptResponse) ProtoMessage() {}
+
+func (x *ReceiptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiptResponse.ProtoReflect.Descriptor instead.
+func (*ReceiptResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ReceiptResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *ReceiptResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetReceiptsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"` // empty means the default room
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReceiptsRequest) Reset() {
+	*x = GetReceiptsRequest{}
+	mi := &file_proto_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReceiptsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReceiptsRequest) ProtoMessage() {}
+
+func (x *GetReceiptsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReceiptsRequest.ProtoReflect.Descriptor instead.
+func (*GetReceiptsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetReceiptsRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *GetReceiptsRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *GetReceiptsRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+type GetReceiptsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeliveredTo   []string               `protobuf:"bytes,1,rep,name=delivered_to,json=deliveredTo,proto3" json:"delivered_to,omitempty"` // sorted, the readers included
+	ReadBy        []string               `protobuf:"bytes,2,rep,name=read_by,json=readBy,proto3" json:"read_by,omitempty"`                // sorted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReceiptsResponse) Reset() {
+	*x = GetReceiptsResponse{}
+	mi := &file_proto_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReceiptsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReceiptsResponse) ProtoMessage() {}
+
+func (x *GetReceiptsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReceiptsResponse.ProtoReflect.Descriptor instead.
+func (*GetReceiptsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetReceiptsResponse) GetDeliveredTo() []string {
+	if x != nil {
+		return x.DeliveredTo
+	}
+	return nil
+}
+
+func (x *GetReceiptsResponse) GetReadBy() []string {
+	if x != nil {
+		return x.ReadBy
+	}
+	return nil
+}
+
+type ListParticipantsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Room          string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"` // empty means the default room
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListParticipantsRequest) Reset() {
+	*x = ListParticipantsRequest{}
+	mi := &file_proto_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListParticipantsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListParticipantsRequest) ProtoMessage() {}
+
+func (x *ListParticipantsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListParticipantsRequest.ProtoReflect.Descriptor instead.
+func (*ListParticipantsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListParticipantsRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type Participant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	JoinedAt      int64                  `protobuf:"varint,2,opt,name=joined_at,json=joinedAt,proto3" json:"joined_at,omitempty"`         // logical time of their JOIN, 0 if this server did not see it
+	JoinedAtMs    int64                  `protobuf:"varint,3,opt,name=joined_at_ms,json=joinedAtMs,proto3" json:"joined_at_ms,omitempty"` // unix milliseconds when this server saw the JOIN, 0 if it did not
+	Status        ParticipantStatus      `protobuf:"varint,4,opt,name=status,proto3,enum=ParticipantStatus" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Participant) Reset() {
+	*x = Participant{}
+	mi := &file_proto_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Participant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Participant) ProtoMessage() {}
+
+func (x *Participant) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Participant.ProtoReflect.Descriptor instead.
+func (*Participant) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *Participant) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *Participant) GetJoinedAt() int64 {
+	if x != nil {
+		return x.JoinedAt
+	}
+	return 0
+}
+
+func (x *Participant) GetJoinedAtMs() int64 {
+	if x != nil {
+		return x.JoinedAtMs
+	}
+	return 0
+}
+
+func (x *Participant) GetStatus() ParticipantStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ParticipantStatus_ONLINE
+}
+
+type ListParticipantsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Participants  []*Participant         `protobuf:"bytes,1,rep,name=participants,proto3" json:"participants,omitempty"` // sorted by ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListParticipantsResponse) Reset() {
+	*x = ListParticipantsResponse{}
+	mi := &file_proto_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListParticipantsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListParticipantsResponse) ProtoMessage() {}
+
+func (x *ListParticipantsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListParticipantsResponse.ProtoReflect.Descriptor instead.
+func (*ListParticipantsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ListParticipantsResponse) GetParticipants() []*Participant {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+// SetStatusRequest changes the status of a participant in a room, like Publish it needs the session token
+type SetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Room          string                 `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`                             // empty means the default room
+	Status        ParticipantStatus      `protobuf:"varint,3,opt,name=status,proto3,enum=ParticipantStatus" json:"status,omitempty"` // ONLINE or AWAY, IDLE is up to the server
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                  // senders Lamport Clock, merged by the server
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetStatusRequest) Reset() {
+	*x = SetStatusRequest{}
+	mi := &file_proto_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetStatusRequest) ProtoMessage() {}
+
+func (x *SetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetStatusRequest.ProtoReflect.Descriptor instead.
+func (*SetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *SetStatusRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *SetStatusRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *SetStatusRequest) GetStatus() ParticipantStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ParticipantStatus_ONLINE
+}
+
+func (x *SetStatusRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type SetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetStatusResponse) Reset() {
+	*x = SetStatusResponse{}
+	mi := &file_proto_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetStatusResponse) ProtoMessage() {}
+
+func (x *SetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetStatusResponse.ProtoReflect.Descriptor instead.
+func (*SetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *SetStatusResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *SetStatusResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListMembersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Room          string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"` // empty means the default room
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMembersRequest) Reset() {
+	*x = ListMembersRequest{}
+	mi := &file_proto_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMembersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMembersRequest) ProtoMessage() {}
+
+func (x *ListMembersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMembersRequest.ProtoReflect.Descriptor instead.
+func (*ListMembersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ListMembersRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type ListMembersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []string               `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMembersResponse) Reset() {
+	*x = ListMembersResponse{}
+	mi := &file_proto_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMembersResponse) ProtoMessage() {}
+
+func (x *ListMembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMembersResponse.ProtoReflect.Descriptor instead.
+func (*ListMembersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ListMembersResponse) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type LeaveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ack           bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaveResponse) Reset() {
+	*x = LeaveResponse{}
+	mi := &file_proto_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *LeaveResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LeaveResponse) ProtoMessage() {}
+func (*LeaveResponse) ProtoMessage() {}
+
+func (x *LeaveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveResponse.ProtoReflect.Descriptor instead.
+func (*LeaveResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *LeaveResponse) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *LeaveResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type FollowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BackupId      string                 `protobuf:"bytes,1,opt,name=backup_id,json=backupId,proto3" json:"backup_id,omitempty"` // only used in the logs
+	From          int64                  `protobuf:"varint,2,opt,name=from,proto3" json:"from,omitempty"`                        // number of history events the backup already has
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FollowRequest) Reset() {
+	*x = FollowRequest{}
+	mi := &file_proto_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FollowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FollowRequest) ProtoMessage() {}
+
+func (x *FollowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FollowRequest.ProtoReflect.Descriptor instead.
+func (*FollowRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *FollowRequest) GetBackupId() string {
+	if x != nil {
+		return x.BackupId
+	}
+	return ""
+}
+
+func (x *FollowRequest) GetFrom() int64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+// ReplicationEvent is one step of the primary's state. broadcast is set for everything that
+// went into the history, room and clock always say where that rooms Lamport clock is now.
+// An event with no room is a heartbeat
+type ReplicationEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int64                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`        // history events on the primary including this one
+	Broadcast     *BroadCast             `protobuf:"bytes,2,opt,name=broadcast,proto3" json:"broadcast,omitempty"` // unset for a clock update or a new room
+	Room          string                 `protobuf:"bytes,3,opt,name=room,proto3" json:"room,omitempty"`
+	Clock         int64                  `protobuf:"varint,4,opt,name=clock,proto3" json:"clock,omitempty"` // the rooms Lamport clock after this event
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicationEvent) Reset() {
+	*x = ReplicationEvent{}
+	mi := &file_proto_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationEvent) ProtoMessage() {}
+
+func (x *ReplicationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationEvent.ProtoReflect.Descriptor instead.
+func (*ReplicationEvent) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ReplicationEvent) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ReplicationEvent) GetBroadcast() *BroadCast {
+	if x != nil {
+		return x.Broadcast
+	}
+	return nil
+}
+
+func (x *ReplicationEvent) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *ReplicationEvent) GetClock() int64 {
+	if x != nil {
+		return x.Clock
+	}
+	return 0
+}
+
+// ChatCommand is one entry of the Raft-replicated chat log. Every node applies the
+// committed commands in the same order, stamping the events with the rooms Lamport clock
+type ChatCommand struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Command:
+	//
+	//	*ChatCommand_Event
+	//	*ChatCommand_CreateRoom
+	//	*ChatCommand_Restarted
+	//	*ChatCommand_Receipt
+	Command       isChatCommand_Command `protobuf_oneof:"command"`
+	Node          string                `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`         // the run of the node that proposed it: node ID, "@" and its start time
+	Proposal      string                `protobuf:"bytes,6,opt,name=proposal,proto3" json:"proposal,omitempty"` // numbers the proposals of that run, a retry keeps its number
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatCommand) Reset() {
+	*x = ChatCommand{}
+	mi := &file_proto_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatCommand) ProtoMessage() {}
+
+func (x *ChatCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatCommand.ProtoReflect.Descriptor instead.
+func (*ChatCommand) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ChatCommand) GetCommand() isChatCommand_Command {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+func (x *ChatCommand) GetEvent() *BroadCast {
+	if x != nil {
+		if x, ok := x.Command.(*ChatCommand_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+func (x *ChatCommand) GetCreateRoom() string {
+	if x != nil {
+		if x, ok := x.Command.(*ChatCommand_CreateRoom); ok {
+			return x.CreateRoom
+		}
+	}
+	return ""
+}
+
+func (x *ChatCommand) GetRestarted() string {
+	if x != nil {
+		if x, ok := x.Command.(*ChatCommand_Restarted); ok {
+			return x.Restarted
+		}
+	}
+	return ""
+}
+
+func (x *ChatCommand) GetReceipt() *ReceiptRequest {
+	if x != nil {
+		if x, ok := x.Command.(*ChatCommand_Receipt); ok {
+			return x.Receipt
+		}
+	}
+	return nil
+}
+
+func (x *ChatCommand) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *ChatCommand) GetProposal() string {
+	if x != nil {
+		return x.Proposal
+	}
+	return ""
+}
+
+type isChatCommand_Command interface {
+	isChatCommand_Command()
+}
+
+type ChatCommand_Event struct {
+	Event *BroadCast `protobuf:"bytes,1,opt,name=event,proto3,oneof"` // JOIN, LEAVE, a message or a change to one, timestamp holds the senders clock
+}
+
+type ChatCommand_CreateRoom struct {
+	CreateRoom string `protobuf:"bytes,2,opt,name=create_room,json=createRoom,proto3,oneof"`
+}
+
+type ChatCommand_Restarted struct {
+	Restarted string `protobuf:"bytes,4,opt,name=restarted,proto3,oneof"` // node ID: its earlier runs are gone, and everyone who was only on them
+}
+
+type ChatCommand_Receipt struct {
+	Receipt *ReceiptRequest `protobuf:"bytes,5,opt,name=receipt,proto3,oneof"` // acks of a participant, client_id and room are checked
+}
+
+func (*ChatCommand_Event) isChatCommand_Command() {}
+
+func (*ChatCommand_CreateRoom) isChatCommand_Command() {}
+
+func (*ChatCommand_Restarted) isChatCommand_Command() {}
+
+func (*ChatCommand_Receipt) isChatCommand_Command() {}
+
+// RoomState and ChatSnapshot are the chat state machine in a Raft snapshot
+type RoomState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Clock         int64                  `protobuf:"varint,2,opt,name=clock,proto3" json:"clock,omitempty"`
+	Vector        map[string]int64       `protobuf:"bytes,3,rep,name=vector,proto3" json:"vector,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Present       []*Presence            `protobuf:"bytes,4,rep,name=present,proto3" json:"present,omitempty"`
+	Receipts      []*ReceiptState        `protobuf:"bytes,5,rep,name=receipts,proto3" json:"receipts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoomState) Reset() {
+	*x = RoomState{}
+	mi := &file_proto_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomState) ProtoMessage() {}
+
+func (x *RoomState) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomState.ProtoReflect.Descriptor instead.
+func (*RoomState) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *RoomState) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RoomState) GetClock() int64 {
+	if x != nil {
+		return x.Clock
+	}
+	return 0
+}
+
+func (x *RoomState) GetVector() map[string]int64 {
+	if x != nil {
+		return x.Vector
+	}
+	return nil
+}
+
+func (x *RoomState) GetPresent() []*Presence {
+	if x != nil {
+		return x.Present
+	}
+	return nil
+}
+
+func (x *RoomState) GetReceipts() []*ReceiptState {
+	if x != nil {
+		return x.Receipts
+	}
+	return nil
+}
+
+// ReceiptState is who got and who read one message
+type ReceiptState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Author        string                 `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	Delivered     []string               `protobuf:"bytes,3,rep,name=delivered,proto3" json:"delivered,omitempty"`
+	Read          []string               `protobuf:"bytes,4,rep,name=read,proto3" json:"read,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiptState) Reset() {
+	*x = ReceiptState{}
+	mi := &file_proto_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiptState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiptState) ProtoMessage() {}
+
+func (x *ReceiptState) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiptState.ProtoReflect.Descriptor instead.
+func (*ReceiptState) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *ReceiptState) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *ReceiptState) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *ReceiptState) GetDelivered() []string {
+	if x != nil {
+		return x.Delivered
+	}
+	return nil
+}
+
+func (x *ReceiptState) GetRead() []string {
+	if x != nil {
+		return x.Read
+	}
+	return nil
+}
+
+// Presence is a participant subscribed on one run of a node
+type Presence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Node          string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Presence) Reset() {
+	*x = Presence{}
+	mi := &file_proto_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Presence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Presence) ProtoMessage() {}
+
+func (x *Presence) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Presence.ProtoReflect.Descriptor instead.
+func (*Presence) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *Presence) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *Presence) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+// Proposal is a command already applied, see ChatCommand
+type Proposal struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          string                 `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Proposal      string                 `protobuf:"bytes,2,opt,name=proposal,proto3" json:"proposal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Proposal) Reset() {
+	*x = Proposal{}
+	mi := &file_proto_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Proposal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Proposal) ProtoMessage() {}
+
+func (x *Proposal) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Proposal.ProtoReflect.Descriptor instead.
+func (*Proposal) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *Proposal) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *Proposal) GetProposal() string {
+	if x != nil {
+		return x.Proposal
+	}
+	return ""
+}
+
+type ChatSnapshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*BroadCast           `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Rooms         []*RoomState           `protobuf:"bytes,2,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	Proposals     []*Proposal            `protobuf:"bytes,3,rep,name=proposals,proto3" json:"proposals,omitempty"` // the recent ones, oldest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatSnapshot) Reset() {
+	*x = ChatSnapshot{}
+	mi := &file_proto_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatSnapshot) ProtoMessage() {}
+
+func (x *ChatSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatSnapshot.ProtoReflect.Descriptor instead.
+func (*ChatSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ChatSnapshot) GetEvents() []*BroadCast {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *ChatSnapshot) GetRooms() []*RoomState {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+func (x *ChatSnapshot) GetProposals() []*Proposal {
+	if x != nil {
+		return x.Proposals
+	}
+	return nil
+}
+
+type RaftEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint64                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Term          uint64                 `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Kind          RaftEntry_Kind         `protobuf:"varint,3,opt,name=kind,proto3,enum=RaftEntry_Kind" json:"kind,omitempty"`
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftEntry) Reset() {
+	*x = RaftEntry{}
+	mi := &file_proto_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftEntry) ProtoMessage() {}
+
+func (x *RaftEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftEntry.ProtoReflect.Descriptor instead.
+func (*RaftEntry) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *RaftEntry) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *RaftEntry) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftEntry) GetKind() RaftEntry_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return RaftEntry_COMMAND
+}
+
+func (x *RaftEntry) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type RaftMember struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addr          string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"` // where its Raft service listens
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftMember) Reset() {
+	*x = RaftMember{}
+	mi := &file_proto_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftMember) ProtoMessage() {}
+
+func (x *RaftMember) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftMember.ProtoReflect.Descriptor instead.
+func (*RaftMember) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *RaftMember) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RaftMember) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+type RaftConfig struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []*RaftMember          `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftConfig) Reset() {
+	*x = RaftConfig{}
+	mi := &file_proto_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftConfig) ProtoMessage() {}
+
+func (x *RaftConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftConfig.ProtoReflect.Descriptor instead.
+func (*RaftConfig) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *RaftConfig) GetMembers() []*RaftMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type RaftState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	VotedFor      string                 `protobuf:"bytes,2,opt,name=voted_for,json=votedFor,proto3" json:"voted_for,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftState) Reset() {
+	*x = RaftState{}
+	mi := &file_proto_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftState) ProtoMessage() {}
+
+func (x *RaftState) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftState.ProtoReflect.Descriptor instead.
+func (*RaftState) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *RaftState) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftState) GetVotedFor() string {
+	if x != nil {
+		return x.VotedFor
+	}
+	return ""
+}
+
+type RaftSnapshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint64                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"` // last entry the snapshot covers
+	Term          uint64                 `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Config        *RaftConfig            `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"` // the state machine
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftSnapshot) Reset() {
+	*x = RaftSnapshot{}
+	mi := &file_proto_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftSnapshot) ProtoMessage() {}
+
+func (x *RaftSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftSnapshot.ProtoReflect.Descriptor instead.
+func (*RaftSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *RaftSnapshot) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *RaftSnapshot) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftSnapshot) GetConfig() *RaftConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *RaftSnapshot) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type VoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Candidate     string                 `protobuf:"bytes,2,opt,name=candidate,proto3" json:"candidate,omitempty"`
+	LastIndex     uint64                 `protobuf:"varint,3,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	LastTerm      uint64                 `protobuf:"varint,4,opt,name=last_term,json=lastTerm,proto3" json:"last_term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteRequest) Reset() {
+	*x = VoteRequest{}
+	mi := &file_proto_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteRequest) ProtoMessage() {}
+
+func (x *VoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteRequest.ProtoReflect.Descriptor instead.
+func (*VoteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *VoteRequest) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *VoteRequest) GetCandidate() string {
+	if x != nil {
+		return x.Candidate
+	}
+	return ""
+}
+
+func (x *VoteRequest) GetLastIndex() uint64 {
+	if x != nil {
+		return x.LastIndex
+	}
+	return 0
+}
+
+func (x *VoteRequest) GetLastTerm() uint64 {
+	if x != nil {
+		return x.LastTerm
+	}
+	return 0
+}
+
+type VoteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Granted       bool                   `protobuf:"varint,2,opt,name=granted,proto3" json:"granted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteResponse) Reset() {
+	*x = VoteResponse{}
+	mi := &file_proto_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteResponse) ProtoMessage() {}
+
+func (x *VoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteResponse.ProtoReflect.Descriptor instead.
+func (*VoteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *VoteResponse) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *VoteResponse) GetGranted() bool {
+	if x != nil {
+		return x.Granted
+	}
+	return false
+}
+
+type AppendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Leader        string                 `protobuf:"bytes,2,opt,name=leader,proto3" json:"leader,omitempty"`
+	PrevIndex     uint64                 `protobuf:"varint,3,opt,name=prev_index,json=prevIndex,proto3" json:"prev_index,omitempty"`
+	PrevTerm      uint64                 `protobuf:"varint,4,opt,name=prev_term,json=prevTerm,proto3" json:"prev_term,omitempty"`
+	Entries       []*RaftEntry           `protobuf:"bytes,5,rep,name=entries,proto3" json:"entries,omitempty"` // empty for a heartbeat
+	Commit        uint64                 `protobuf:"varint,6,opt,name=commit,proto3" json:"commit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendRequest) Reset() {
+	*x = AppendRequest{}
+	mi := &file_proto_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendRequest) ProtoMessage() {}
+
+func (x *AppendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendRequest.ProtoReflect.Descriptor instead.
+func (*AppendRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *AppendRequest) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *AppendRequest) GetLeader() string {
+	if x != nil {
+		return x.Leader
+	}
+	return ""
+}
+
+func (x *AppendRequest) GetPrevIndex() uint64 {
+	if x != nil {
+		return x.PrevIndex
+	}
+	return 0
+}
+
+func (x *AppendRequest) GetPrevTerm() uint64 {
+	if x != nil {
+		return x.PrevTerm
+	}
+	return 0
+}
+
+func (x *AppendRequest) GetEntries() []*RaftEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *AppendRequest) GetCommit() uint64 {
+	if x != nil {
+		return x.Commit
+	}
+	return 0
+}
+
+type AppendResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	ConflictIndex uint64                 `protobuf:"varint,3,opt,name=conflict_index,json=conflictIndex,proto3" json:"conflict_index,omitempty"` // on failure: where the leader should continue
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendResponse) Reset() {
+	*x = AppendResponse{}
+	mi := &file_proto_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendResponse) ProtoMessage() {}
+
+func (x *AppendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendResponse.ProtoReflect.Descriptor instead.
+func (*AppendResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *AppendResponse) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *AppendResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AppendResponse) GetConflictIndex() uint64 {
+	if x != nil {
+		return x.ConflictIndex
+	}
+	return 0
+}
+
+type SnapshotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Leader        string                 `protobuf:"bytes,2,opt,name=leader,proto3" json:"leader,omitempty"`
+	Snapshot      *RaftSnapshot          `protobuf:"bytes,3,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotRequest) Reset() {
+	*x = SnapshotRequest{}
+	mi := &file_proto_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotRequest) ProtoMessage() {}
 
-func (x *LeaveResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_proto_msgTypes[5]
+func (x *SnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[50]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -363,57 +3588,693 @@ func (x *LeaveResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LeaveResponse.ProtoReflect.Descriptor instead.
-func (*LeaveResponse) Descriptor() ([]byte, []int) {
-	return file_proto_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use SnapshotRequest.ProtoReflect.Descriptor instead.
+func (*SnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{50}
 }
 
-func (x *LeaveResponse) GetAck() bool {
+func (x *SnapshotRequest) GetTerm() uint64 {
 	if x != nil {
-		return x.Ack
+		return x.Term
 	}
-	return false
+	return 0
 }
 
-func (x *LeaveResponse) GetError() string {
+func (x *SnapshotRequest) GetLeader() string {
+	if x != nil {
+		return x.Leader
+	}
+	return ""
+}
+
+func (x *SnapshotRequest) GetSnapshot() *RaftSnapshot {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+type SnapshotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotResponse) Reset() {
+	*x = SnapshotResponse{}
+	mi := &file_proto_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotResponse) ProtoMessage() {}
+
+func (x *SnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotResponse.ProtoReflect.Descriptor instead.
+func (*SnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *SnapshotResponse) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+// ProposeRequest asks the leader to append to the log: a command, or a membership change
+type ProposeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Command       []byte                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Add           *RaftMember            `protobuf:"bytes,2,opt,name=add,proto3" json:"add,omitempty"`
+	Remove        string                 `protobuf:"bytes,3,opt,name=remove,proto3" json:"remove,omitempty"` // id of the member to remove
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProposeRequest) Reset() {
+	*x = ProposeRequest{}
+	mi := &file_proto_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProposeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProposeRequest) ProtoMessage() {}
+
+func (x *ProposeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProposeRequest.ProtoReflect.Descriptor instead.
+func (*ProposeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *ProposeRequest) GetCommand() []byte {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+func (x *ProposeRequest) GetAdd() *RaftMember {
+	if x != nil {
+		return x.Add
+	}
+	return nil
+}
+
+func (x *ProposeRequest) GetRemove() string {
+	if x != nil {
+		return x.Remove
+	}
+	return ""
+}
+
+type ProposeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint64                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"` // where it was committed
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Leader        string                 `protobuf:"bytes,3,opt,name=leader,proto3" json:"leader,omitempty"` // set when the node asked is not the leader
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProposeResponse) Reset() {
+	*x = ProposeResponse{}
+	mi := &file_proto_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProposeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProposeResponse) ProtoMessage() {}
+
+func (x *ProposeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProposeResponse.ProtoReflect.Descriptor instead.
+func (*ProposeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *ProposeResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ProposeResponse) GetError() string {
 	if x != nil {
 		return x.Error
 	}
 	return ""
 }
 
+func (x *ProposeResponse) GetLeader() string {
+	if x != nil {
+		return x.Leader
+	}
+	return ""
+}
+
+// FederationHello opens a Federate stream from both sides
+type FederationHello struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`                                                                        // name of the server saying hello
+	Seen          map[string]int64       `protobuf:"bytes,2,rep,name=seen,proto3" json:"seen,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // origin server -> highest event ID it already has
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationHello) Reset() {
+	*x = FederationHello{}
+	mi := &file_proto_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationHello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationHello) ProtoMessage() {}
+
+func (x *FederationHello) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationHello.ProtoReflect.Descriptor instead.
+func (*FederationHello) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *FederationHello) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *FederationHello) GetSeen() map[string]int64 {
+	if x != nil {
+		return x.Seen
+	}
+	return nil
+}
+
+type FederationMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*FederationMessage_Hello
+	//	*FederationMessage_Event
+	Message       isFederationMessage_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationMessage) Reset() {
+	*x = FederationMessage{}
+	mi := &file_proto_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationMessage) ProtoMessage() {}
+
+func (x *FederationMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationMessage.ProtoReflect.Descriptor instead.
+func (*FederationMessage) Descriptor() ([]byte, []int) {
+	return file_proto_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *FederationMessage) GetMessage() isFederationMessage_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *FederationMessage) GetHello() *FederationHello {
+	if x != nil {
+		if x, ok := x.Message.(*FederationMessage_Hello); ok {
+			return x.Hello
+		}
+	}
+	return nil
+}
+
+func (x *FederationMessage) GetEvent() *BroadCast {
+	if x != nil {
+		if x, ok := x.Message.(*FederationMessage_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+type isFederationMessage_Message interface {
+	isFederationMessage_Message()
+}
+
+type FederationMessage_Hello struct {
+	Hello *FederationHello `protobuf:"bytes,1,opt,name=hello,proto3,oneof"`
+}
+
+type FederationMessage_Event struct {
+	Event *BroadCast `protobuf:"bytes,2,opt,name=event,proto3,oneof"`
+}
+
+func (*FederationMessage_Hello) isFederationMessage_Message() {}
+
+func (*FederationMessage_Event) isFederationMessage_Message() {}
+
 var File_proto_proto protoreflect.FileDescriptor
 
 const file_proto_proto_rawDesc = "" +
 	"\n" +
-	"\vproto.proto\"\xac\x01\n" +
+	"\vproto.proto\"\xca\b\n" +
 	"\tBroadCast\x12#\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x0f.BroadCast.TypeR\x04type\x12\x1b\n" +
 	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x18\n" +
 	"\amessage\x18\x03 \x01(\tR\amessage\x12\x1c\n" +
-	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"%\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12.\n" +
+	"\x06vector\x18\x05 \x03(\v2\x16.BroadCast.VectorEntryR\x06vector\x12\x12\n" +
+	"\x04room\x18\x06 \x01(\tR\x04room\x12\x1c\n" +
+	"\trecipient\x18\a \x01(\tR\trecipient\x12,\n" +
+	"\x12reconnect_after_ms\x18\b \x01(\x03R\x10reconnectAfterMs\x12\x17\n" +
+	"\aack_seq\x18\t \x01(\x03R\x06ackSeq\x12\x14\n" +
+	"\x05error\x18\n" +
+	" \x01(\tR\x05error\x12\x16\n" +
+	"\x06origin\x18\v \x01(\tR\x06origin\x12\x19\n" +
+	"\bevent_id\x18\f \x01(\x03R\aeventId\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\r \x01(\tR\tmessageId\x12\x16\n" +
+	"\x06edited\x18\x0e \x01(\bR\x06edited\x12\x18\n" +
+	"\adeleted\x18\x0f \x01(\bR\adeleted\x12\x1b\n" +
+	"\tparent_id\x18\x10 \x01(\tR\bparentId\x12\x1a\n" +
+	"\breaction\x18\x11 \x01(\tR\breaction\x12\x18\n" +
+	"\aremoved\x18\x12 \x01(\bR\aremoved\x127\n" +
+	"\treactions\x18\x13 \x03(\v2\x19.BroadCast.ReactionsEntryR\treactions\x12*\n" +
+	"\x06status\x18\x14 \x01(\x0e2\x12.ParticipantStatusR\x06status\x12\x1c\n" +
+	"\tdelivered\x18\x15 \x01(\x05R\tdelivered\x12\x12\n" +
+	"\x04read\x18\x16 \x01(\x05R\x04read\x12,\n" +
+	"\n" +
+	"error_code\x18\x17 \x01(\x0e2\r.PublishErrorR\terrorCode\x12\x1a\n" +
+	"\bprevious\x18\x18 \x01(\x03R\bprevious\x1a9\n" +
+	"\vVectorEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a<\n" +
+	"\x0eReactionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xd2\x01\n" +
 	"\x04Type\x12\b\n" +
 	"\x04CHAT\x10\x00\x12\b\n" +
 	"\x04JOIN\x10\x01\x12\t\n" +
-	"\x05LEAVE\x10\x02\"\"\n" +
+	"\x05LEAVE\x10\x02\x12\n" +
+	"\n" +
+	"\x06DIRECT\x10\x03\x12\x13\n" +
+	"\x0fSERVER_SHUTDOWN\x10\x04\x12\r\n" +
+	"\tHEARTBEAT\x10\x05\x12\a\n" +
+	"\x03ACK\x10\x06\x12\b\n" +
+	"\x04EDIT\x10\a\x12\n" +
+	"\n" +
+	"\x06DELETE\x10\b\x12\f\n" +
+	"\bREACTION\x10\t\x12\x10\n" +
+	"\fTYPING_START\x10\n" +
+	"\x12\x0f\n" +
+	"\vTYPING_STOP\x10\v\x12\f\n" +
+	"\bPRESENCE\x10\f\x12\v\n" +
+	"\aRECEIPT\x10\r\x12\x10\n" +
+	"\fROOM_CREATED\x10\x0e\"v\n" +
 	"\x10SubscribeRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"A\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12'\n" +
+	"\x0fsince_timestamp\x18\x02 \x01(\x03R\x0esinceTimestamp\x12\x15\n" +
+	"\x06last_n\x18\x03 \x01(\x05R\x05lastN\x12\x12\n" +
+	"\x04room\x18\x04 \x01(\tR\x04room\"\xbd\x02\n" +
 	"\x0ePublishRequest\x12\x1b\n" +
 	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
-	"\x04text\x18\x02 \x01(\tR\x04text\"9\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x123\n" +
+	"\x06vector\x18\x04 \x03(\v2\x1b.PublishRequest.VectorEntryR\x06vector\x12\x12\n" +
+	"\x04room\x18\x05 \x01(\tR\x04room\x12\x1c\n" +
+	"\trecipient\x18\x06 \x01(\tR\trecipient\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\a \x01(\tR\tmessageId\x12\x1b\n" +
+	"\tparent_id\x18\b \x01(\tR\bparentId\x1a9\n" +
+	"\vVectorEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x86\x01\n" +
 	"\x0fPublishResponse\x12\x10\n" +
 	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"+\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\x12,\n" +
+	"\n" +
+	"error_code\x18\x04 \x01(\x0e2\r.PublishErrorR\terrorCode\"|\n" +
 	"\fLeaveRequest\x12\x1b\n" +
-	"\tclient_id\x18\x01 \x01(\tR\bclientId\"7\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x12\n" +
+	"\x04room\x18\x03 \x01(\tR\x04room\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x04 \x01(\tR\tsessionId\"X\n" +
+	"\rTypingRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12\x16\n" +
+	"\x06typing\x18\x03 \x01(\bR\x06typing\"8\n" +
+	"\x0eTypingResponse\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xcf\x01\n" +
+	"\vClientEvent\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x03R\x03seq\x12'\n" +
+	"\x04join\x18\x02 \x01(\v2\x11.SubscribeRequestH\x00R\x04join\x12+\n" +
+	"\apublish\x18\x03 \x01(\v2\x0f.PublishRequestH\x00R\apublish\x12(\n" +
+	"\x06typing\x18\x04 \x01(\v2\x0e.TypingRequestH\x00R\x06typing\x12%\n" +
+	"\x05leave\x18\x05 \x01(\v2\r.LeaveRequestH\x00R\x05leaveB\a\n" +
+	"\x05event\"\x8f\x01\n" +
+	"\vEditRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"d\n" +
+	"\fEditResponse\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12,\n" +
+	"\n" +
+	"error_code\x18\x03 \x01(\x0e2\r.PublishErrorR\terrorCode\"}\n" +
+	"\rDeleteRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"8\n" +
+	"\x0eDeleteResponse\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"b\n" +
+	"\x10GetThreadRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\"_\n" +
+	"\x11GetThreadResponse\x12$\n" +
+	"\amessage\x18\x01 \x01(\v2\n" +
+	".BroadCastR\amessage\x12$\n" +
+	"\areplies\x18\x02 \x03(\v2\n" +
+	".BroadCastR\areplies\"\x9b\x01\n" +
+	"\x0fReactionRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\x12\x1a\n" +
+	"\breaction\x18\x04 \x01(\tR\breaction\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"\xb8\x01\n" +
+	"\x10ReactionResponse\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12>\n" +
+	"\treactions\x18\x03 \x03(\v2 .ReactionResponse.ReactionsEntryR\treactions\x1a<\n" +
+	"\x0eReactionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"'\n" +
+	"\x11CreateRoomRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"<\n" +
+	"\x12CreateRoomResponse\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x12\n" +
+	"\x10ListRoomsRequest\"8\n" +
+	"\bRoomInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\amembers\x18\x02 \x01(\x05R\amembers\"4\n" +
+	"\x11ListRoomsResponse\x12\x1f\n" +
+	"\x05rooms\x18\x01 \x03(\v2\t.RoomInfoR\x05rooms\"s\n" +
+	"\x0eReceiptRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12\x1c\n" +
+	"\tdelivered\x18\x03 \x03(\tR\tdelivered\x12\x12\n" +
+	"\x04read\x18\x04 \x03(\tR\x04read\"9\n" +
+	"\x0fReceiptResponse\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"d\n" +
+	"\x12GetReceiptsRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\"Q\n" +
+	"\x13GetReceiptsResponse\x12!\n" +
+	"\fdelivered_to\x18\x01 \x03(\tR\vdeliveredTo\x12\x17\n" +
+	"\aread_by\x18\x02 \x03(\tR\x06readBy\"-\n" +
+	"\x17ListParticipantsRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\"\x95\x01\n" +
+	"\vParticipant\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x1b\n" +
+	"\tjoined_at\x18\x02 \x01(\x03R\bjoinedAt\x12 \n" +
+	"\fjoined_at_ms\x18\x03 \x01(\x03R\n" +
+	"joinedAtMs\x12*\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x12.ParticipantStatusR\x06status\"L\n" +
+	"\x18ListParticipantsResponse\x120\n" +
+	"\fparticipants\x18\x01 \x03(\v2\f.ParticipantR\fparticipants\"\x8d\x01\n" +
+	"\x10SetStatusRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x12*\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x12.ParticipantStatusR\x06status\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\";\n" +
+	"\x11SetStatusResponse\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"(\n" +
+	"\x12ListMembersRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\"/\n" +
+	"\x13ListMembersResponse\x12\x18\n" +
+	"\amembers\x18\x01 \x03(\tR\amembers\"7\n" +
 	"\rLeaveResponse\x12\x10\n" +
 	"\x03ack\x18\x01 \x01(\bR\x03ack\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error2\x94\x01\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"@\n" +
+	"\rFollowRequest\x12\x1b\n" +
+	"\tbackup_id\x18\x01 \x01(\tR\bbackupId\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\x03R\x04from\"|\n" +
+	"\x10ReplicationEvent\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x03R\x05index\x12(\n" +
+	"\tbroadcast\x18\x02 \x01(\v2\n" +
+	".BroadCastR\tbroadcast\x12\x12\n" +
+	"\x04room\x18\x03 \x01(\tR\x04room\x12\x14\n" +
+	"\x05clock\x18\x04 \x01(\x03R\x05clock\"\xdc\x01\n" +
+	"\vChatCommand\x12\"\n" +
+	"\x05event\x18\x01 \x01(\v2\n" +
+	".BroadCastH\x00R\x05event\x12!\n" +
+	"\vcreate_room\x18\x02 \x01(\tH\x00R\n" +
+	"createRoom\x12\x1e\n" +
+	"\trestarted\x18\x04 \x01(\tH\x00R\trestarted\x12+\n" +
+	"\areceipt\x18\x05 \x01(\v2\x0f.ReceiptRequestH\x00R\areceipt\x12\x12\n" +
+	"\x04node\x18\x03 \x01(\tR\x04node\x12\x1a\n" +
+	"\bproposal\x18\x06 \x01(\tR\bproposalB\t\n" +
+	"\acommand\"\xf0\x01\n" +
+	"\tRoomState\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05clock\x18\x02 \x01(\x03R\x05clock\x12.\n" +
+	"\x06vector\x18\x03 \x03(\v2\x16.RoomState.VectorEntryR\x06vector\x12#\n" +
+	"\apresent\x18\x04 \x03(\v2\t.PresenceR\apresent\x12)\n" +
+	"\breceipts\x18\x05 \x03(\v2\r.ReceiptStateR\breceipts\x1a9\n" +
+	"\vVectorEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"w\n" +
+	"\fReceiptState\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x16\n" +
+	"\x06author\x18\x02 \x01(\tR\x06author\x12\x1c\n" +
+	"\tdelivered\x18\x03 \x03(\tR\tdelivered\x12\x12\n" +
+	"\x04read\x18\x04 \x03(\tR\x04read\";\n" +
+	"\bPresence\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\":\n" +
+	"\bProposal\x12\x12\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\x12\x1a\n" +
+	"\bproposal\x18\x02 \x01(\tR\bproposal\"}\n" +
+	"\fChatSnapshot\x12\"\n" +
+	"\x06events\x18\x01 \x03(\v2\n" +
+	".BroadCastR\x06events\x12 \n" +
+	"\x05rooms\x18\x02 \x03(\v2\n" +
+	".RoomStateR\x05rooms\x12'\n" +
+	"\tproposals\x18\x03 \x03(\v2\t.ProposalR\tproposals\"\x99\x01\n" +
+	"\tRaftEntry\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x04R\x04term\x12#\n" +
+	"\x04kind\x18\x03 \x01(\x0e2\x0f.RaftEntry.KindR\x04kind\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\")\n" +
+	"\x04Kind\x12\v\n" +
+	"\aCOMMAND\x10\x00\x12\n" +
+	"\n" +
+	"\x06CONFIG\x10\x01\x12\b\n" +
+	"\x04NOOP\x10\x02\"0\n" +
+	"\n" +
+	"RaftMember\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\"3\n" +
+	"\n" +
+	"RaftConfig\x12%\n" +
+	"\amembers\x18\x01 \x03(\v2\v.RaftMemberR\amembers\"<\n" +
+	"\tRaftState\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x1b\n" +
+	"\tvoted_for\x18\x02 \x01(\tR\bvotedFor\"q\n" +
+	"\fRaftSnapshot\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x04R\x04term\x12#\n" +
+	"\x06config\x18\x03 \x01(\v2\v.RaftConfigR\x06config\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\"{\n" +
+	"\vVoteRequest\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x1c\n" +
+	"\tcandidate\x18\x02 \x01(\tR\tcandidate\x12\x1d\n" +
+	"\n" +
+	"last_index\x18\x03 \x01(\x04R\tlastIndex\x12\x1b\n" +
+	"\tlast_term\x18\x04 \x01(\x04R\blastTerm\"<\n" +
+	"\fVoteResponse\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x18\n" +
+	"\agranted\x18\x02 \x01(\bR\agranted\"\xb5\x01\n" +
+	"\rAppendRequest\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x16\n" +
+	"\x06leader\x18\x02 \x01(\tR\x06leader\x12\x1d\n" +
+	"\n" +
+	"prev_index\x18\x03 \x01(\x04R\tprevIndex\x12\x1b\n" +
+	"\tprev_term\x18\x04 \x01(\x04R\bprevTerm\x12$\n" +
+	"\aentries\x18\x05 \x03(\v2\n" +
+	".RaftEntryR\aentries\x12\x16\n" +
+	"\x06commit\x18\x06 \x01(\x04R\x06commit\"e\n" +
+	"\x0eAppendResponse\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12%\n" +
+	"\x0econflict_index\x18\x03 \x01(\x04R\rconflictIndex\"h\n" +
+	"\x0fSnapshotRequest\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x16\n" +
+	"\x06leader\x18\x02 \x01(\tR\x06leader\x12)\n" +
+	"\bsnapshot\x18\x03 \x01(\v2\r.RaftSnapshotR\bsnapshot\"&\n" +
+	"\x10SnapshotResponse\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\"a\n" +
+	"\x0eProposeRequest\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\fR\acommand\x12\x1d\n" +
+	"\x03add\x18\x02 \x01(\v2\v.RaftMemberR\x03add\x12\x16\n" +
+	"\x06remove\x18\x03 \x01(\tR\x06remove\"U\n" +
+	"\x0fProposeResponse\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x16\n" +
+	"\x06leader\x18\x03 \x01(\tR\x06leader\"\x92\x01\n" +
+	"\x0fFederationHello\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12.\n" +
+	"\x04seen\x18\x02 \x03(\v2\x1a.FederationHello.SeenEntryR\x04seen\x1a7\n" +
+	"\tSeenEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"l\n" +
+	"\x11FederationMessage\x12(\n" +
+	"\x05hello\x18\x01 \x01(\v2\x10.FederationHelloH\x00R\x05hello\x12\"\n" +
+	"\x05event\x18\x02 \x01(\v2\n" +
+	".BroadCastH\x00R\x05eventB\t\n" +
+	"\amessage*\x80\x01\n" +
+	"\fPublishError\x12\f\n" +
+	"\bNO_ERROR\x10\x00\x12\x10\n" +
+	"\fINVALID_UTF8\x10\x01\x12\x11\n" +
+	"\rEMPTY_MESSAGE\x10\x02\x12\x17\n" +
+	"\x13TOO_MANY_CHARACTERS\x10\x03\x12\x12\n" +
+	"\x0eTOO_MANY_BYTES\x10\x04\x12\x10\n" +
+	"\fBLOCKED_WORD\x10\x05*3\n" +
+	"\x11ParticipantStatus\x12\n" +
+	"\n" +
+	"\x06ONLINE\x10\x00\x12\b\n" +
+	"\x04AWAY\x10\x01\x12\b\n" +
+	"\x04IDLE\x10\x022\x8c\a\n" +
 	"\bChitChat\x12.\n" +
 	"\tSubscribe\x12\x11.SubscribeRequest\x1a\n" +
 	".BroadCast\"\x000\x01\x12.\n" +
 	"\aPublish\x12\x0f.PublishRequest\x1a\x10.PublishResponse\"\x00\x12(\n" +
-	"\x05Leave\x12\r.LeaveRequest\x1a\x0e.LeaveResponse\"\x00B\x15Z\x13ChitChat/grpc/protob\x06proto3"
+	"\x05Leave\x12\r.LeaveRequest\x1a\x0e.LeaveResponse\"\x00\x12&\n" +
+	"\x04Chat\x12\f.ClientEvent\x1a\n" +
+	".BroadCast\"\x00(\x010\x01\x127\n" +
+	"\n" +
+	"CreateRoom\x12\x12.CreateRoomRequest\x1a\x13.CreateRoomResponse\"\x00\x124\n" +
+	"\tListRooms\x12\x11.ListRoomsRequest\x1a\x12.ListRoomsResponse\"\x00\x12:\n" +
+	"\vListMembers\x12\x13.ListMembersRequest\x1a\x14.ListMembersResponse\"\x00\x12I\n" +
+	"\x10ListParticipants\x12\x18.ListParticipantsRequest\x1a\x19.ListParticipantsResponse\"\x00\x124\n" +
+	"\tSetStatus\x12\x11.SetStatusRequest\x1a\x12.SetStatusResponse\"\x00\x122\n" +
+	"\vAcknowledge\x12\x0f.ReceiptRequest\x1a\x10.ReceiptResponse\"\x00\x12:\n" +
+	"\vGetReceipts\x12\x13.GetReceiptsRequest\x1a\x14.GetReceiptsResponse\"\x00\x12,\n" +
+	"\vEditMessage\x12\f.EditRequest\x1a\r.EditResponse\"\x00\x122\n" +
+	"\rDeleteMessage\x12\x0e.DeleteRequest\x1a\x0f.DeleteResponse\"\x00\x124\n" +
+	"\tGetThread\x12\x11.GetThreadRequest\x1a\x12.GetThreadResponse\"\x00\x124\n" +
+	"\vAddReaction\x12\x10.ReactionRequest\x1a\x11.ReactionResponse\"\x00\x127\n" +
+	"\x0eRemoveReaction\x12\x10.ReactionRequest\x1a\x11.ReactionResponse\"\x00\x12+\n" +
+	"\x06Typing\x12\x0e.TypingRequest\x1a\x0f.TypingResponse\"\x002>\n" +
+	"\vReplication\x12/\n" +
+	"\x06Follow\x12\x0e.FollowRequest\x1a\x11.ReplicationEvent\"\x000\x012\xd2\x01\n" +
+	"\x04Raft\x12,\n" +
+	"\vRequestVote\x12\f.VoteRequest\x1a\r.VoteResponse\"\x00\x122\n" +
+	"\rAppendEntries\x12\x0e.AppendRequest\x1a\x0f.AppendResponse\"\x00\x128\n" +
+	"\x0fInstallSnapshot\x12\x10.SnapshotRequest\x1a\x11.SnapshotResponse\"\x00\x12.\n" +
+	"\aPropose\x12\x0f.ProposeRequest\x1a\x10.ProposeResponse\"\x002F\n" +
+	"\n" +
+	"Federation\x128\n" +
+	"\bFederate\x12\x12.FederationMessage\x1a\x12.FederationMessage\"\x00(\x010\x01B\x15Z\x13ChitChat/grpc/protob\x06proto3"
 
 var (
 	file_proto_proto_rawDescOnce sync.Once
@@ -427,30 +4288,165 @@ func file_proto_proto_rawDescGZIP() []byte {
 	return file_proto_proto_rawDescData
 }
 
-var file_proto_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_proto_proto_msgTypes = make([]protoimpl.MessageInfo, 62)
 var file_proto_proto_goTypes = []any{
-	(BroadCast_Type)(0),      // 0: BroadCast.Type
-	(*BroadCast)(nil),        // 1: BroadCast
-	(*SubscribeRequest)(nil), // 2: SubscribeRequest
-	(*PublishRequest)(nil),   // 3: PublishRequest
-	(*PublishResponse)(nil),  // 4: PublishResponse
-	(*LeaveRequest)(nil),     // 5: LeaveRequest
-	(*LeaveResponse)(nil),    // 6: LeaveResponse
+	(PublishError)(0),                // 0: PublishError
+	(ParticipantStatus)(0),           // 1: ParticipantStatus
+	(BroadCast_Type)(0),              // 2: BroadCast.Type
+	(RaftEntry_Kind)(0),              // 3: RaftEntry.Kind
+	(*BroadCast)(nil),                // 4: BroadCast
+	(*SubscribeRequest)(nil),         // 5: SubscribeRequest
+	(*PublishRequest)(nil),           // 6: PublishRequest
+	(*PublishResponse)(nil),          // 7: PublishResponse
+	(*LeaveRequest)(nil),             // 8: LeaveRequest
+	(*TypingRequest)(nil),            // 9: TypingRequest
+	(*TypingResponse)(nil),           // 10: TypingResponse
+	(*ClientEvent)(nil),              // 11: ClientEvent
+	(*EditRequest)(nil),              // 12: EditRequest
+	(*EditResponse)(nil),             // 13: EditResponse
+	(*DeleteRequest)(nil),            // 14: DeleteRequest
+	(*DeleteResponse)(nil),           // 15: DeleteResponse
+	(*GetThreadRequest)(nil),         // 16: GetThreadRequest
+	(*GetThreadResponse)(nil),        // 17: GetThreadResponse
+	(*ReactionRequest)(nil),          // 18: ReactionRequest
+	(*ReactionResponse)(nil),         // 19: ReactionResponse
+	(*CreateRoomRequest)(nil),        // 20: CreateRoomRequest
+	(*CreateRoomResponse)(nil),       // 21: CreateRoomResponse
+	(*ListRoomsRequest)(nil),         // 22: ListRoomsRequest
+	(*RoomInfo)(nil),                 // 23: RoomInfo
+	(*ListRoomsResponse)(nil),        // 24: ListRoomsResponse
+	(*ReceiptRequest)(nil),           // 25: ReceiptRequest
+	(*ReceiptResponse)(nil),          // 26: ReceiptResponse
+	(*GetReceiptsRequest)(nil),       // 27: GetReceiptsRequest
+	(*GetReceiptsResponse)(nil),      // 28: GetReceiptsResponse
+	(*ListParticipantsRequest)(nil),  // 29: ListParticipantsRequest
+	(*Participant)(nil),              // 30: Participant
+	(*ListParticipantsResponse)(nil), // 31: ListParticipantsResponse
+	(*SetStatusRequest)(nil),         // 32: SetStatusRequest
+	(*SetStatusResponse)(nil),        // 33: SetStatusResponse
+	(*ListMembersRequest)(nil),       // 34: ListMembersRequest
+	(*ListMembersResponse)(nil),      // 35: ListMembersResponse
+	(*LeaveResponse)(nil),            // 36: LeaveResponse
+	(*FollowRequest)(nil),            // 37: FollowRequest
+	(*ReplicationEvent)(nil),         // 38: ReplicationEvent
+	(*ChatCommand)(nil),              // 39: ChatCommand
+	(*RoomState)(nil),                // 40: RoomState
+	(*ReceiptState)(nil),             // 41: ReceiptState
+	(*Presence)(nil),                 // 42: Presence
+	(*Proposal)(nil),                 // 43: Proposal
+	(*ChatSnapshot)(nil),             // 44: ChatSnapshot
+	(*RaftEntry)(nil),                // 45: RaftEntry
+	(*RaftMember)(nil),               // 46: RaftMember
+	(*RaftConfig)(nil),               // 47: RaftConfig
+	(*RaftState)(nil),                // 48: RaftState
+	(*RaftSnapshot)(nil),             // 49: RaftSnapshot
+	(*VoteRequest)(nil),              // 50: VoteRequest
+	(*VoteResponse)(nil),             // 51: VoteResponse
+	(*AppendRequest)(nil),            // 52: AppendRequest
+	(*AppendResponse)(nil),           // 53: AppendResponse
+	(*SnapshotRequest)(nil),          // 54: SnapshotRequest
+	(*SnapshotResponse)(nil),         // 55: SnapshotResponse
+	(*ProposeRequest)(nil),           // 56: ProposeRequest
+	(*ProposeResponse)(nil),          // 57: ProposeResponse
+	(*FederationHello)(nil),          // 58: FederationHello
+	(*FederationMessage)(nil),        // 59: FederationMessage
+	nil,                              // 60: BroadCast.VectorEntry
+	nil,                              // 61: BroadCast.ReactionsEntry
+	nil,                              // 62: PublishRequest.VectorEntry
+	nil,                              // 63: ReactionResponse.ReactionsEntry
+	nil,                              // 64: RoomState.VectorEntry
+	nil,                              // 65: FederationHello.SeenEntry
 }
 var file_proto_proto_depIdxs = []int32{
-	0, // 0: BroadCast.type:type_name -> BroadCast.Type
-	2, // 1: ChitChat.Subscribe:input_type -> SubscribeRequest
-	3, // 2: ChitChat.Publish:input_type -> PublishRequest
-	5, // 3: ChitChat.Leave:input_type -> LeaveRequest
-	1, // 4: ChitChat.Subscribe:output_type -> BroadCast
-	4, // 5: ChitChat.Publish:output_type -> PublishResponse
-	6, // 6: ChitChat.Leave:output_type -> LeaveResponse
-	4, // [4:7] is the sub-list for method output_type
-	1, // [1:4] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	2,  // 0: BroadCast.type:type_name -> BroadCast.Type
+	60, // 1: BroadCast.vector:type_name -> BroadCast.VectorEntry
+	61, // 2: BroadCast.reactions:type_name -> BroadCast.ReactionsEntry
+	1,  // 3: BroadCast.status:type_name -> ParticipantStatus
+	0,  // 4: BroadCast.error_code:type_name -> PublishError
+	62, // 5: PublishRequest.vector:type_name -> PublishRequest.VectorEntry
+	0,  // 6: PublishResponse.error_code:type_name -> PublishError
+	5,  // 7: ClientEvent.join:type_name -> SubscribeRequest
+	6,  // 8: ClientEvent.publish:type_name -> PublishRequest
+	9,  // 9: ClientEvent.typing:type_name -> TypingRequest
+	8,  // 10: ClientEvent.leave:type_name -> LeaveRequest
+	0,  // 11: EditResponse.error_code:type_name -> PublishError
+	4,  // 12: GetThreadResponse.message:type_name -> BroadCast
+	4,  // 13: GetThreadResponse.replies:type_name -> BroadCast
+	63, // 14: ReactionResponse.reactions:type_name -> ReactionResponse.ReactionsEntry
+	23, // 15: ListRoomsResponse.rooms:type_name -> RoomInfo
+	1,  // 16: Participant.status:type_name -> ParticipantStatus
+	30, // 17: ListParticipantsResponse.participants:type_name -> Participant
+	1,  // 18: SetStatusRequest.status:type_name -> ParticipantStatus
+	4,  // 19: ReplicationEvent.broadcast:type_name -> BroadCast
+	4,  // 20: ChatCommand.event:type_name -> BroadCast
+	25, // 21: ChatCommand.receipt:type_name -> ReceiptRequest
+	64, // 22: RoomState.vector:type_name -> RoomState.VectorEntry
+	42, // 23: RoomState.present:type_name -> Presence
+	41, // 24: RoomState.receipts:type_name -> ReceiptState
+	4,  // 25: ChatSnapshot.events:type_name -> BroadCast
+	40, // 26: ChatSnapshot.rooms:type_name -> RoomState
+	43, // 27: ChatSnapshot.proposals:type_name -> Proposal
+	3,  // 28: RaftEntry.kind:type_name -> RaftEntry.Kind
+	46, // 29: RaftConfig.members:type_name -> RaftMember
+	47, // 30: RaftSnapshot.config:type_name -> RaftConfig
+	45, // 31: AppendRequest.entries:type_name -> RaftEntry
+	49, // 32: SnapshotRequest.snapshot:type_name -> RaftSnapshot
+	46, // 33: ProposeRequest.add:type_name -> RaftMember
+	65, // 34: FederationHello.seen:type_name -> FederationHello.SeenEntry
+	58, // 35: FederationMessage.hello:type_name -> FederationHello
+	4,  // 36: FederationMessage.event:type_name -> BroadCast
+	5,  // 37: ChitChat.Subscribe:input_type -> SubscribeRequest
+	6,  // 38: ChitChat.Publish:input_type -> PublishRequest
+	8,  // 39: ChitChat.Leave:input_type -> LeaveRequest
+	11, // 40: ChitChat.Chat:input_type -> ClientEvent
+	20, // 41: ChitChat.CreateRoom:input_type -> CreateRoomRequest
+	22, // 42: ChitChat.ListRooms:input_type -> ListRoomsRequest
+	34, // 43: ChitChat.ListMembers:input_type -> ListMembersRequest
+	29, // 44: ChitChat.ListParticipants:input_type -> ListParticipantsRequest
+	32, // 45: ChitChat.SetStatus:input_type -> SetStatusRequest
+	25, // 46: ChitChat.Acknowledge:input_type -> ReceiptRequest
+	27, // 47: ChitChat.GetReceipts:input_type -> GetReceiptsRequest
+	12, // 48: ChitChat.EditMessage:input_type -> EditRequest
+	14, // 49: ChitChat.DeleteMessage:input_type -> DeleteRequest
+	16, // 50: ChitChat.GetThread:input_type -> GetThreadRequest
+	18, // 51: ChitChat.AddReaction:input_type -> ReactionRequest
+	18, // 52: ChitChat.RemoveReaction:input_type -> ReactionRequest
+	9,  // 53: ChitChat.Typing:input_type -> TypingRequest
+	37, // 54: Replication.Follow:input_type -> FollowRequest
+	50, // 55: Raft.RequestVote:input_type -> VoteRequest
+	52, // 56: Raft.AppendEntries:input_type -> AppendRequest
+	54, // 57: Raft.InstallSnapshot:input_type -> SnapshotRequest
+	56, // 58: Raft.Propose:input_type -> ProposeRequest
+	59, // 59: Federation.Federate:input_type -> FederationMessage
+	4,  // 60: ChitChat.Subscribe:output_type -> BroadCast
+	7,  // 61: ChitChat.Publish:output_type -> PublishResponse
+	36, // 62: ChitChat.Leave:output_type -> LeaveResponse
+	4,  // 63: ChitChat.Chat:output_type -> BroadCast
+	21, // 64: ChitChat.CreateRoom:output_type -> CreateRoomResponse
+	24, // 65: ChitChat.ListRooms:output_type -> ListRoomsResponse
+	35, // 66: ChitChat.ListMembers:output_type -> ListMembersResponse
+	31, // 67: ChitChat.ListParticipants:output_type -> ListParticipantsResponse
+	33, // 68: ChitChat.SetStatus:output_type -> SetStatusResponse
+	26, // 69: ChitChat.Acknowledge:output_type -> ReceiptResponse
+	28, // 70: ChitChat.GetReceipts:output_type -> GetReceiptsResponse
+	13, // 71: ChitChat.EditMessage:output_type -> EditResponse
+	15, // 72: ChitChat.DeleteMessage:output_type -> DeleteResponse
+	17, // 73: ChitChat.GetThread:output_type -> GetThreadResponse
+	19, // 74: ChitChat.AddReaction:output_type -> ReactionResponse
+	19, // 75: ChitChat.RemoveReaction:output_type -> ReactionResponse
+	10, // 76: ChitChat.Typing:output_type -> TypingResponse
+	38, // 77: Replication.Follow:output_type -> ReplicationEvent
+	51, // 78: Raft.RequestVote:output_type -> VoteResponse
+	53, // 79: Raft.AppendEntries:output_type -> AppendResponse
+	55, // 80: Raft.InstallSnapshot:output_type -> SnapshotResponse
+	57, // 81: Raft.Propose:output_type -> ProposeResponse
+	59, // 82: Federation.Federate:output_type -> FederationMessage
+	60, // [60:83] is the sub-list for method output_type
+	37, // [37:60] is the sub-list for method input_type
+	37, // [37:37] is the sub-list for extension type_name
+	37, // [37:37] is the sub-list for extension extendee
+	0,  // [0:37] is the sub-list for field type_name
 }
 
 func init() { file_proto_proto_init() }
@@ -458,15 +4454,31 @@ func file_proto_proto_init() {
 	if File_proto_proto != nil {
 		return
 	}
+	file_proto_proto_msgTypes[7].OneofWrappers = []any{
+		(*ClientEvent_Join)(nil),
+		(*ClientEvent_Publish)(nil),
+		(*ClientEvent_Typing)(nil),
+		(*ClientEvent_Leave)(nil),
+	}
+	file_proto_proto_msgTypes[35].OneofWrappers = []any{
+		(*ChatCommand_Event)(nil),
+		(*ChatCommand_CreateRoom)(nil),
+		(*ChatCommand_Restarted)(nil),
+		(*ChatCommand_Receipt)(nil),
+	}
+	file_proto_proto_msgTypes[55].OneofWrappers = []any{
+		(*FederationMessage_Hello)(nil),
+		(*FederationMessage_Event)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_proto_rawDesc), len(file_proto_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   6,
+			NumEnums:      4,
+			NumMessages:   62,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   4,
 		},
 		GoTypes:           file_proto_proto_goTypes,
 		DependencyIndexes: file_proto_proto_depIdxs,
@@ -0,0 +1,967 @@
+// Package raft is a small Raft implementation: leader election, log replication,
+// snapshots for log compaction and membership changes one server at a time.
+// ChitChat servers use it to agree on one order of chat events across a cluster
+package raft
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const maxBatch = 64 // entries per AppendEntries
+
+var (
+	// ErrNoLeader means no leader is known right now, e.g. during an election
+	ErrNoLeader = errors.New("raft: no leader, try again")
+	// ErrLost means the entry was overwritten by a new leader before it was committed
+	ErrLost = errors.New("raft: lost in a leader change, try again")
+	// ErrStopped means the node was stopped
+	ErrStopped = errors.New("raft: node stopped")
+
+	errNotLeader = errors.New("raft: not the leader")
+)
+
+type role int
+
+const (
+	follower role = iota
+	candidate
+	leader
+)
+
+func (r role) String() string {
+	switch r {
+	case candidate:
+		return "candidate"
+	case leader:
+		return "leader"
+	}
+	return "follower"
+}
+
+// Config sets up a node
+type Config struct {
+	ID string
+	// Members is the cluster to start with, including this node. It is only used on a
+	// fresh start, leave it empty for a node that is added to a running cluster
+	Members   []*proto.RaftMember
+	Transport Transport
+	Storage   Storage
+
+	// Apply gets every committed command, in log order and one at a time
+	Apply func(command []byte)
+	// Snapshot returns the state machine as of the last applied command, Restore replaces it
+	Snapshot func() ([]byte, error)
+	Restore  func(data []byte) error
+
+	SnapshotEvery     uint64        // compact the log after this many applied entries, 0 never does
+	HeartbeatInterval time.Duration // how often the leader contacts every follower
+	ElectionTimeout   time.Duration // followers wait between this and twice this for the leader
+}
+
+// waiter is a proposal on the leader waiting for its entry to be applied
+type waiter struct {
+	term uint64
+	done chan error
+}
+
+// Node is one member of a Raft cluster
+type Node struct {
+	cfg Config
+
+	mutex    sync.Mutex
+	role     role
+	term     uint64
+	votedFor string
+	leader   string // ID of the leader we know of, empty if none
+
+	snapshot *proto.RaftSnapshot // everything up to snapshot.Index is compacted into it
+	log      []*proto.RaftEntry  // entries after the snapshot
+	members  []*proto.RaftMember // latest configuration in the log, used right away
+
+	commitIndex uint64
+	lastApplied uint64
+	restore     *proto.RaftSnapshot // installed from the leader, waiting to be applied
+
+	votes      map[string]bool
+	nextIndex  map[string]uint64
+	matchIndex map[string]uint64
+	sentCommit map[string]uint64
+	inflight   map[string]bool
+	deadline   time.Time // when a follower starts an election
+	contact    time.Time // when we last heard from a leader
+	heartbeat  time.Time // when the leader contacts its followers next
+
+	waiters map[uint64]waiter
+	applied chan struct{} // closed and replaced whenever lastApplied moves
+	wake    chan struct{} // tells the apply loop there is work
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// Start loads the node from its storage and starts taking part in the cluster
+func Start(cfg Config) (*Node, error) {
+	n, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	n.Run()
+	return n, nil
+}
+
+// New loads the node from its storage without starting it yet, so whatever Apply needs
+// can be set up with the node before the first command comes in
+func New(cfg Config) (*Node, error) {
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 50 * time.Millisecond
+	}
+	if cfg.ElectionTimeout <= 0 {
+		cfg.ElectionTimeout = 10 * cfg.HeartbeatInterval
+	}
+
+	state, snapshot, entries, err := cfg.Storage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("raft: load %s: %w", cfg.ID, err)
+	}
+	n := &Node{
+		cfg:        cfg,
+		snapshot:   snapshot,
+		log:        entries,
+		nextIndex:  make(map[string]uint64),
+		matchIndex: make(map[string]uint64),
+		sentCommit: make(map[string]uint64),
+		inflight:   make(map[string]bool),
+		waiters:    make(map[uint64]waiter),
+		applied:    make(chan struct{}),
+		wake:       make(chan struct{}, 1),
+		stopped:    make(chan struct{}),
+	}
+	if state != nil {
+		n.term, n.votedFor = state.Term, state.VotedFor
+	}
+	if n.snapshot == nil {
+		// Fresh start: the configuration we were given is where the log begins
+		n.snapshot = &proto.RaftSnapshot{Config: &proto.RaftConfig{Members: cfg.Members}}
+		if err := cfg.Storage.SaveSnapshot(n.snapshot); err != nil {
+			return nil, err
+		}
+	} else if len(n.snapshot.Data) > 0 {
+		if err := cfg.Restore(n.snapshot.Data); err != nil {
+			return nil, fmt.Errorf("raft: restore %s: %w", cfg.ID, err)
+		}
+	}
+	n.commitIndex = n.snapshot.Index
+	n.lastApplied = n.snapshot.Index
+	n.members = n.configAt(n.lastIndex())
+	n.resetDeadline()
+
+	log.Printf("Raft %s STARTUP: term=%d snapshot=%d log=%d members=%s",
+		cfg.ID, n.term, n.snapshot.Index, len(n.log), memberIDs(n.members))
+	return n, nil
+}
+
+// Run starts taking part in the cluster and applying committed commands, in the background
+func (n *Node) Run() {
+	go n.run()
+	go n.applyLoop()
+}
+
+// Stop makes the node stop taking part, it does not leave the cluster
+func (n *Node) Stop() {
+	n.once.Do(func() { close(n.stopped) })
+}
+
+// ID returns the nodes ID
+func (n *Node) ID() string {
+	return n.cfg.ID
+}
+
+// Status returns the role, term and leader as this node sees them
+func (n *Node) Status() (role string, term uint64, leader string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.role.String(), n.term, n.leader
+}
+
+// Members returns the latest configuration this node knows of
+func (n *Node) Members() []*proto.RaftMember {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return append([]*proto.RaftMember(nil), n.members...)
+}
+
+// Propose appends a command to the log through the leader and returns once this node
+// applied it. It may be applied even if an error comes back, e.g. on a timeout
+func (n *Node) Propose(ctx context.Context, command []byte) error {
+	return n.propose(ctx, &proto.ProposeRequest{Command: command})
+}
+
+// AddMember adds a node to the cluster, it catches up from the leader
+func (n *Node) AddMember(ctx context.Context, member *proto.RaftMember) error {
+	return n.propose(ctx, &proto.ProposeRequest{Add: member})
+}
+
+// RemoveMember takes a node out of the cluster
+func (n *Node) RemoveMember(ctx context.Context, id string) error {
+	return n.propose(ctx, &proto.ProposeRequest{Remove: id})
+}
+
+// propose submits to the log here if we lead, otherwise through the leader
+func (n *Node) propose(ctx context.Context, req *proto.ProposeRequest) error {
+	index, err := n.submitAndWait(ctx, req)
+	if errors.Is(err, errNotLeader) {
+		leader := n.leaderMember()
+		if leader == nil {
+			return ErrNoLeader
+		}
+		response, err := n.cfg.Transport.Propose(ctx, leader, req)
+		if err != nil {
+			return err
+		}
+		if response.Error != "" {
+			return errors.New(response.Error)
+		}
+		index = response.Index
+	} else if err != nil {
+		return err
+	}
+	return n.waitApplied(ctx, index)
+}
+
+// HandlePropose answers a proposal from another node once it is committed. A node that
+// does not lead passes it on to the leader, so joining nodes can ask any member
+func (n *Node) HandlePropose(ctx context.Context, req *proto.ProposeRequest) *proto.ProposeResponse {
+	index, err := n.submitAndWait(ctx, req)
+	if errors.Is(err, errNotLeader) {
+		if leader := n.leaderMember(); leader != nil && leader.Id != n.cfg.ID {
+			if response, err := n.cfg.Transport.Propose(ctx, leader, req); err == nil {
+				return response
+			}
+		}
+	}
+	if err != nil {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		return &proto.ProposeResponse{Error: err.Error(), Leader: n.leader}
+	}
+	return &proto.ProposeResponse{Index: index}
+}
+
+// mayPropose checks a proposal against the node that sent it
+func (n *Node) mayPropose(caller string, req *proto.ProposeRequest) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if req.Remove != "" && req.Remove != caller {
+		return fmt.Errorf("raft: %s may only remove itself", caller)
+	}
+	if findMember(n.members, caller) != nil {
+		return nil
+	}
+	if req.Add == nil || req.Add.Id != caller || len(req.Command) > 0 {
+		return fmt.Errorf("raft: %s is not a member and may only ask to be added itself", caller)
+	}
+	return nil
+}
+
+// submitAndWait appends to the log if we are the leader and waits until it is applied here
+func (n *Node) submitAndWait(ctx context.Context, req *proto.ProposeRequest) (uint64, error) {
+	n.mutex.Lock()
+	index, err := n.submit(req)
+	if err != nil {
+		n.mutex.Unlock()
+		return 0, err
+	}
+	w := waiter{term: n.term, done: make(chan error, 1)}
+	n.waiters[index] = w
+	n.mutex.Unlock()
+
+	select {
+	case err := <-w.done:
+		return index, err
+	case <-ctx.Done():
+		return index, ctx.Err()
+	case <-n.stopped:
+		return index, ErrStopped
+	}
+}
+
+// submit appends a proposal to the leaders log. Must be called with n.mutex held
+func (n *Node) submit(req *proto.ProposeRequest) (uint64, error) {
+	if n.role != leader {
+		return 0, errNotLeader
+	}
+
+	entry := &proto.RaftEntry{Index: n.lastIndex() + 1, Term: n.term, Data: req.Command}
+	if req.Add != nil || req.Remove != "" {
+		members, err := n.changeMembers(req)
+		if err != nil {
+			return 0, err
+		}
+		data, err := protobuf.Marshal(&proto.RaftConfig{Members: members})
+		if err != nil {
+			return 0, err
+		}
+		entry.Kind = proto.RaftEntry_CONFIG
+		entry.Data = data
+	}
+
+	if err := n.append(entry); err != nil {
+		return 0, err
+	}
+	n.matchIndex[n.cfg.ID] = entry.Index
+	n.advanceCommit()
+	n.replicateAll()
+	return entry.Index, nil
+}
+
+// changeMembers returns the configuration after one server is added or removed.
+// Only one change may be in flight, and only once this leader committed an entry
+// of its own term. Must be called with n.mutex held
+func (n *Node) changeMembers(req *proto.ProposeRequest) ([]*proto.RaftMember, error) {
+	if n.termAt(n.commitIndex) != n.term {
+		return nil, errors.New("raft: leader not settled yet, try again")
+	}
+	for i := n.commitIndex + 1; i <= n.lastIndex(); i++ {
+		if n.entry(i).Kind == proto.RaftEntry_CONFIG {
+			return nil, errors.New("raft: another membership change is in progress, try again")
+		}
+	}
+
+	members := append([]*proto.RaftMember(nil), n.members...)
+	if req.Add != nil {
+		if findMember(members, req.Add.Id) != nil {
+			return nil, fmt.Errorf("raft: %s is already a member", req.Add.Id)
+		}
+		return append(members, req.Add), nil
+	}
+	for i, member := range members {
+		if member.Id == req.Remove {
+			return append(members[:i], members[i+1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("raft: %s is not a member", req.Remove)
+}
+
+// waitApplied blocks until this node applied the entry at index
+func (n *Node) waitApplied(ctx context.Context, index uint64) error {
+	for {
+		n.mutex.Lock()
+		done := n.lastApplied >= index
+		applied := n.applied
+		n.mutex.Unlock()
+		if done {
+			return nil
+		}
+		select {
+		case <-applied:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-n.stopped:
+			return ErrStopped
+		}
+	}
+}
+
+// run drives elections and heartbeats
+func (n *Node) run() {
+	ticker := time.NewTicker(n.cfg.HeartbeatInterval / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stopped:
+			return
+		case now := <-ticker.C:
+			n.mutex.Lock()
+			if n.role == leader {
+				if now.After(n.heartbeat) {
+					n.heartbeat = now.Add(n.cfg.HeartbeatInterval)
+					n.sentCommit = make(map[string]uint64) // forces a message to everyone
+					n.replicateAll()
+				}
+			} else if now.After(n.deadline) && findMember(n.members, n.cfg.ID) != nil {
+				// Nodes that are not (yet) in the configuration never start elections
+				n.campaign()
+			}
+			n.mutex.Unlock()
+		}
+	}
+}
+
+// campaign starts an election. Must be called with n.mutex held
+func (n *Node) campaign() {
+	n.role = candidate
+	n.term++
+	n.votedFor = n.cfg.ID
+	n.leader = ""
+	n.persistState()
+	n.resetDeadline()
+	n.votes = map[string]bool{n.cfg.ID: true}
+	log.Printf("Raft %s ELECTION: term=%d", n.cfg.ID, n.term)
+
+	if n.quorum(n.votes) {
+		n.becomeLeader()
+		return
+	}
+	req := &proto.VoteRequest{
+		Term:      n.term,
+		Candidate: n.cfg.ID,
+		LastIndex: n.lastIndex(),
+		LastTerm:  n.termAt(n.lastIndex()),
+	}
+	for _, peer := range n.peers() {
+		go n.requestVote(peer, req)
+	}
+}
+
+func (n *Node) requestVote(peer *proto.RaftMember, req *proto.VoteRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.cfg.ElectionTimeout)
+	defer cancel()
+	response, err := n.cfg.Transport.RequestVote(ctx, peer, req)
+	if err != nil {
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if response.Term > n.term {
+		n.stepDown(response.Term)
+		return
+	}
+	if n.role != candidate || n.term != req.Term || !response.Granted {
+		return
+	}
+	n.votes[peer.Id] = true
+	if n.quorum(n.votes) {
+		n.becomeLeader()
+	}
+}
+
+// becomeLeader starts leading with a no-op entry, committing it tells us
+// what is committed from earlier terms. Must be called with n.mutex held
+func (n *Node) becomeLeader() {
+	n.role = leader
+	n.leader = n.cfg.ID
+	for _, peer := range n.peers() {
+		n.nextIndex[peer.Id] = n.lastIndex() + 1
+		n.matchIndex[peer.Id] = 0
+	}
+	n.sentCommit = make(map[string]uint64)
+	log.Printf("Raft %s LEADER: term=%d members=%s", n.cfg.ID, n.term, memberIDs(n.members))
+
+	noop := &proto.RaftEntry{Index: n.lastIndex() + 1, Term: n.term, Kind: proto.RaftEntry_NOOP}
+	if err := n.append(noop); err != nil {
+		log.Printf("Raft %s STORAGE_ERROR: %v", n.cfg.ID, err)
+	}
+	n.matchIndex[n.cfg.ID] = noop.Index
+	n.advanceCommit()
+	n.heartbeat = time.Now().Add(n.cfg.HeartbeatInterval)
+	n.replicateAll()
+}
+
+// stepDown turns a leader or candidate into a follower, and takes on a newer term.
+// Must be called with n.mutex held
+func (n *Node) stepDown(term uint64) {
+	if term > n.term {
+		n.term = term
+		n.votedFor = ""
+		n.persistState()
+	}
+	if n.role == leader {
+		log.Printf("Raft %s FOLLOWER: stepping down in term %d", n.cfg.ID, n.term)
+	}
+	n.role = follower
+	n.resetDeadline()
+}
+
+// HandleVote answers a candidate
+func (n *Node) HandleVote(req *proto.VoteRequest) *proto.VoteResponse {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	// While a leader is around, a candidate is one that lost touch (restarted, removed)
+	// and must not depose it
+	if n.role == leader || (n.leader != "" && time.Since(n.contact) < n.cfg.ElectionTimeout) {
+		return &proto.VoteResponse{Term: n.term}
+	}
+	if req.Term > n.term {
+		n.stepDown(req.Term)
+	}
+	//Only vote for a candidate whose log is at least as up to date as ours
+	lastTerm := n.termAt(n.lastIndex())
+	upToDate := req.LastTerm > lastTerm || (req.LastTerm == lastTerm && req.LastIndex >= n.lastIndex())
+	granted := req.Term == n.term && (n.votedFor == "" || n.votedFor == req.Candidate) && upToDate
+	if granted {
+		n.votedFor = req.Candidate
+		n.persistState()
+		n.resetDeadline()
+	}
+	return &proto.VoteResponse{Term: n.term, Granted: granted}
+}
+
+// HandleAppend takes entries (or just a heartbeat) from the leader
+func (n *Node) HandleAppend(req *proto.AppendRequest) *proto.AppendResponse {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if req.Term < n.term {
+		return &proto.AppendResponse{Term: n.term}
+	}
+	n.stepDown(req.Term)
+	n.leader = req.Leader
+	n.contact = time.Now()
+
+	//Our log must hold the entry right before the new ones
+	prev := req.PrevIndex
+	if prev > n.lastIndex() {
+		return &proto.AppendResponse{Term: n.term, ConflictIndex: n.lastIndex() + 1}
+	}
+	if prev >= n.snapshot.Index && n.termAt(prev) != req.PrevTerm {
+		// Skip the whole conflicting term at once
+		conflictTerm := n.termAt(prev)
+		first := prev
+		for first > n.snapshot.Index+1 && n.termAt(first-1) == conflictTerm {
+			first--
+		}
+		return &proto.AppendResponse{Term: n.term, ConflictIndex: first}
+	}
+
+	var added []*proto.RaftEntry
+	truncated := false
+	for _, entry := range req.Entries {
+		if entry.Index <= n.snapshot.Index {
+			continue // already compacted, so committed and the same
+		}
+		if len(added) == 0 && entry.Index <= n.lastIndex() {
+			if n.termAt(entry.Index) == entry.Term {
+				continue
+			}
+			// A different entry from an old leader, drop it and everything after it
+			n.log = n.log[:entry.Index-n.snapshot.Index-1]
+			truncated = true
+		}
+		added = append(added, entry)
+	}
+	n.log = append(n.log, added...)
+	var err error
+	if truncated {
+		err = n.cfg.Storage.Rewrite(n.log)
+	} else if len(added) > 0 {
+		err = n.cfg.Storage.Append(added)
+	}
+	if err != nil {
+		log.Printf("Raft %s STORAGE_ERROR: %v", n.cfg.ID, err)
+		return &proto.AppendResponse{Term: n.term, ConflictIndex: n.snapshot.Index + 1}
+	}
+	if len(added) > 0 {
+		n.members = n.configAt(n.lastIndex())
+	}
+
+	// Only what we know matches the leader can be committed
+	matched := prev + uint64(len(req.Entries))
+	if commit := min(req.Commit, matched); commit > n.commitIndex {
+		n.commitIndex = commit
+		n.kick()
+	}
+	return &proto.AppendResponse{Term: n.term, Success: true}
+}
+
+// HandleSnapshot replaces our log with the leaders snapshot when we fell too far behind
+func (n *Node) HandleSnapshot(req *proto.SnapshotRequest) *proto.SnapshotResponse {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if req.Term < n.term {
+		return &proto.SnapshotResponse{Term: n.term}
+	}
+	n.stepDown(req.Term)
+	n.leader = req.Leader
+	n.contact = time.Now()
+
+	snapshot := req.Snapshot
+	if snapshot.Index <= n.commitIndex {
+		return &proto.SnapshotResponse{Term: n.term}
+	}
+	// Keep the part of our log after the snapshot if it agrees with it
+	if snapshot.Index < n.lastIndex() && n.termAt(snapshot.Index) == snapshot.Term {
+		n.log = n.log[snapshot.Index-n.snapshot.Index:]
+	} else {
+		n.log = nil
+	}
+	n.snapshot = snapshot
+	if err := n.cfg.Storage.SaveSnapshot(snapshot); err != nil {
+		log.Printf("Raft %s STORAGE_ERROR: %v", n.cfg.ID, err)
+	}
+	if err := n.cfg.Storage.Rewrite(n.log); err != nil {
+		log.Printf("Raft %s STORAGE_ERROR: %v", n.cfg.ID, err)
+	}
+	n.members = n.configAt(n.lastIndex())
+	n.commitIndex = snapshot.Index
+	n.restore = snapshot
+	n.kick()
+	log.Printf("Raft %s SNAPSHOT_INSTALLED: index=%d from %s", n.cfg.ID, snapshot.Index, req.Leader)
+	return &proto.SnapshotResponse{Term: n.term}
+}
+
+// replicateAll sends every follower what it is missing. Must be called with n.mutex held
+func (n *Node) replicateAll() {
+	for _, peer := range n.peers() {
+		go n.replicate(peer)
+	}
+}
+
+// replicate sends one follower its next batch of entries, or the snapshot if the
+// entries it needs are compacted, and keeps going while it is behind
+func (n *Node) replicate(peer *proto.RaftMember) {
+	n.mutex.Lock()
+	if n.role != leader || n.inflight[peer.Id] {
+		n.mutex.Unlock()
+		return
+	}
+	next, ok := n.nextIndex[peer.Id]
+	if !ok {
+		next = n.lastIndex() + 1
+		n.nextIndex[peer.Id] = next
+	}
+	if next > n.lastIndex() && n.sentCommit[peer.Id] >= n.commitIndex && n.sentCommit[peer.Id] > 0 {
+		n.mutex.Unlock()
+		return // nothing new
+	}
+	n.inflight[peer.Id] = true
+	term := n.term
+	ctx, cancel := context.WithTimeout(context.Background(), n.cfg.ElectionTimeout)
+	defer cancel()
+
+	if next <= n.snapshot.Index {
+		snapshot := n.snapshot
+		n.mutex.Unlock()
+		response, err := n.cfg.Transport.InstallSnapshot(ctx, peer, &proto.SnapshotRequest{Term: term, Leader: n.cfg.ID, Snapshot: snapshot})
+
+		n.mutex.Lock()
+		n.inflight[peer.Id] = false
+		if err != nil || !n.stillLeading(term, response.Term) {
+			n.mutex.Unlock()
+			return
+		}
+		n.matchIndex[peer.Id] = max(n.matchIndex[peer.Id], snapshot.Index)
+		n.nextIndex[peer.Id] = snapshot.Index + 1
+		n.mutex.Unlock()
+		n.replicate(peer)
+		return
+	}
+
+	end := min(n.lastIndex(), next+maxBatch-1)
+	entries := make([]*proto.RaftEntry, 0, end+1-next)
+	for i := next; i <= end; i++ {
+		entries = append(entries, n.entry(i))
+	}
+	req := &proto.AppendRequest{
+		Term:      term,
+		Leader:    n.cfg.ID,
+		PrevIndex: next - 1,
+		PrevTerm:  n.termAt(next - 1),
+		Entries:   entries,
+		Commit:    n.commitIndex,
+	}
+	n.mutex.Unlock()
+	response, err := n.cfg.Transport.AppendEntries(ctx, peer, req)
+
+	n.mutex.Lock()
+	n.inflight[peer.Id] = false
+	if err != nil || !n.stillLeading(term, response.Term) {
+		n.mutex.Unlock()
+		return
+	}
+	n.sentCommit[peer.Id] = req.Commit
+	if response.Success {
+		match := req.PrevIndex + uint64(len(entries))
+		n.matchIndex[peer.Id] = max(n.matchIndex[peer.Id], match)
+		n.nextIndex[peer.Id] = match + 1
+		n.advanceCommit()
+	} else {
+		n.nextIndex[peer.Id] = max(1, next-1)
+		if response.ConflictIndex > 0 {
+			n.nextIndex[peer.Id] = response.ConflictIndex
+		}
+	}
+	more := n.nextIndex[peer.Id] <= n.lastIndex() || n.sentCommit[peer.Id] < n.commitIndex
+	n.mutex.Unlock()
+	if more {
+		n.replicate(peer)
+	}
+}
+
+// stillLeading handles the term of a response and tells whether we still lead the
+// term the request was sent in. Must be called with n.mutex held
+func (n *Node) stillLeading(term, responseTerm uint64) bool {
+	if responseTerm > n.term {
+		n.stepDown(responseTerm)
+		return false
+	}
+	return n.role == leader && n.term == term
+}
+
+// advanceCommit commits the newest entry of our term that a majority holds.
+// Must be called with n.mutex held
+func (n *Node) advanceCommit() {
+	for index := n.lastIndex(); index > n.commitIndex; index-- {
+		if n.termAt(index) != n.term {
+			break // entries of earlier terms are only committed along with one of ours
+		}
+		holders := make(map[string]bool)
+		for _, member := range n.members {
+			if n.matchIndex[member.Id] >= index {
+				holders[member.Id] = true
+			}
+		}
+		if !n.quorum(holders) {
+			continue
+		}
+		n.commitIndex = index
+		n.kick()
+		n.replicateAll()
+
+		// A leader that removed itself hands over once the change is committed
+		if findMember(n.members, n.cfg.ID) == nil {
+			log.Printf("Raft %s REMOVED: no longer a member, stepping down", n.cfg.ID)
+			n.stepDown(n.term)
+		}
+		return
+	}
+}
+
+// applyLoop hands committed commands to the state machine in order and compacts the log
+func (n *Node) applyLoop() {
+	for {
+		select {
+		case <-n.stopped:
+			n.failWaiters(ErrStopped)
+			return
+		case <-n.wake:
+		}
+
+		n.mutex.Lock()
+		if snapshot := n.restore; snapshot != nil {
+			n.restore = nil
+			n.mutex.Unlock()
+			if err := n.cfg.Restore(snapshot.Data); err != nil {
+				log.Printf("Raft %s RESTORE_ERROR: %v", n.cfg.ID, err)
+			}
+			n.mutex.Lock()
+			n.lastApplied = snapshot.Index
+			n.notifyApplied(snapshot.Index, snapshot.Term)
+		}
+		var entries []*proto.RaftEntry
+		for i := n.lastApplied + 1; i <= n.commitIndex && i > n.snapshot.Index; i++ {
+			entries = append(entries, n.entry(i))
+		}
+		n.mutex.Unlock()
+
+		for _, entry := range entries {
+			if entry.Kind == proto.RaftEntry_COMMAND {
+				n.cfg.Apply(entry.Data)
+			}
+			n.mutex.Lock()
+			n.lastApplied = entry.Index
+			n.notifyApplied(entry.Index, entry.Term)
+			n.mutex.Unlock()
+		}
+		n.compact()
+
+		// More may have been committed while we were applying
+		n.mutex.Lock()
+		if n.lastApplied < n.commitIndex || n.restore != nil {
+			n.kick()
+		}
+		n.mutex.Unlock()
+	}
+}
+
+// compact replaces the applied part of the log with a snapshot once it grew long enough
+func (n *Node) compact() {
+	n.mutex.Lock()
+	index := n.lastApplied
+	if n.cfg.SnapshotEvery == 0 || index < n.snapshot.Index+n.cfg.SnapshotEvery {
+		n.mutex.Unlock()
+		return
+	}
+	n.mutex.Unlock()
+
+	//Only this goroutine changes the state machine, so it is still at index
+	data, err := n.cfg.Snapshot()
+	if err != nil {
+		log.Printf("Raft %s SNAPSHOT_ERROR: %v", n.cfg.ID, err)
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if index <= n.snapshot.Index {
+		return // the leader sent a newer snapshot meanwhile
+	}
+	snapshot := &proto.RaftSnapshot{
+		Index:  index,
+		Term:   n.termAt(index),
+		Config: &proto.RaftConfig{Members: n.configAt(index)},
+		Data:   data,
+	}
+	if err := n.cfg.Storage.SaveSnapshot(snapshot); err != nil {
+		log.Printf("Raft %s STORAGE_ERROR: %v", n.cfg.ID, err)
+		return
+	}
+	n.log = n.log[index-n.snapshot.Index:]
+	n.snapshot = snapshot
+	if err := n.cfg.Storage.Rewrite(n.log); err != nil {
+		log.Printf("Raft %s STORAGE_ERROR: %v", n.cfg.ID, err)
+	}
+	log.Printf("Raft %s SNAPSHOT: compacted the log up to index %d", n.cfg.ID, index)
+}
+
+// notifyApplied wakes everyone waiting for the entry at index. Must be called with n.mutex held
+func (n *Node) notifyApplied(index, term uint64) {
+	for i, w := range n.waiters {
+		if i > index {
+			continue
+		}
+		if i == index && w.term == term {
+			w.done <- nil
+		} else {
+			w.done <- ErrLost
+		}
+		delete(n.waiters, i)
+	}
+	close(n.applied)
+	n.applied = make(chan struct{})
+}
+
+func (n *Node) failWaiters(err error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for i, w := range n.waiters {
+		w.done <- err
+		delete(n.waiters, i)
+	}
+}
+
+// kick wakes the apply loop
+func (n *Node) kick() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// append adds an entry to the leaders log and storage. Must be called with n.mutex held
+func (n *Node) append(entry *proto.RaftEntry) error {
+	if err := n.cfg.Storage.Append([]*proto.RaftEntry{entry}); err != nil {
+		return err
+	}
+	n.log = append(n.log, entry)
+	if entry.Kind == proto.RaftEntry_CONFIG {
+		n.members = n.configAt(entry.Index)
+		for _, peer := range n.peers() {
+			if _, ok := n.nextIndex[peer.Id]; !ok {
+				n.nextIndex[peer.Id] = n.snapshot.Index + 1
+			}
+		}
+		log.Printf("Raft %s MEMBERS: %s (index %d)", n.cfg.ID, memberIDs(n.members), entry.Index)
+	}
+	return nil
+}
+
+func (n *Node) persistState() {
+	if err := n.cfg.Storage.SaveState(&proto.RaftState{Term: n.term, VotedFor: n.votedFor}); err != nil {
+		log.Printf("Raft %s STORAGE_ERROR: %v", n.cfg.ID, err)
+	}
+}
+
+func (n *Node) resetDeadline() {
+	timeout := n.cfg.ElectionTimeout
+	n.deadline = time.Now().Add(timeout + time.Duration(rand.Int63n(int64(timeout))))
+}
+
+func (n *Node) lastIndex() uint64 {
+	return n.snapshot.Index + uint64(len(n.log))
+}
+
+// termAt returns the term of the entry at index, 0 if it is compacted away or missing
+func (n *Node) termAt(index uint64) uint64 {
+	if index == n.snapshot.Index {
+		return n.snapshot.Term
+	}
+	if index < n.snapshot.Index || index > n.lastIndex() {
+		return 0
+	}
+	return n.entry(index).Term
+}
+
+// entry returns the entry at index, which must be after the snapshot
+func (n *Node) entry(index uint64) *proto.RaftEntry {
+	return n.log[index-n.snapshot.Index-1]
+}
+
+// configAt returns the configuration in force at index: the latest one in the log
+// up to there, or the one in the snapshot
+func (n *Node) configAt(index uint64) []*proto.RaftMember {
+	for i := min(index, n.lastIndex()); i > n.snapshot.Index; i-- {
+		if entry := n.entry(i); entry.Kind == proto.RaftEntry_CONFIG {
+			config := &proto.RaftConfig{}
+			if err := protobuf.Unmarshal(entry.Data, config); err == nil {
+				return config.Members
+			}
+		}
+	}
+	return n.snapshot.GetConfig().GetMembers()
+}
+
+// peers returns every member but us
+func (n *Node) peers() []*proto.RaftMember {
+	var peers []*proto.RaftMember
+	for _, member := range n.members {
+		if member.Id != n.cfg.ID {
+			peers = append(peers, member)
+		}
+	}
+	return peers
+}
+
+// quorum tells whether the set holds a majority of the members
+func (n *Node) quorum(set map[string]bool) bool {
+	count := 0
+	for _, member := range n.members {
+		if set[member.Id] {
+			count++
+		}
+	}
+	return count > len(n.members)/2
+}
+
+func (n *Node) leaderMember() *proto.RaftMember {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return findMember(n.members, n.leader)
+}
+
+func findMember(members []*proto.RaftMember, id string) *proto.RaftMember {
+	for _, member := range members {
+		if member.Id == id {
+			return member
+		}
+	}
+	return nil
+}
+
+func memberIDs(members []*proto.RaftMember) []string {
+	ids := make([]string, len(members))
+	for i, member := range members {
+		ids[i] = member.Id
+	}
+	return ids
+}
@@ -0,0 +1,290 @@
+package raft
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// machine is the state machine of a test node: every applied command in order
+type machine struct {
+	mutex   sync.Mutex
+	applied []string
+}
+
+func (m *machine) apply(command []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.applied = append(m.applied, string(command))
+}
+
+func (m *machine) snapshot() ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return []byte(strings.Join(m.applied, ",")), nil
+}
+
+func (m *machine) restore(data []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.applied = nil
+	if len(data) > 0 {
+		m.applied = strings.Split(string(data), ",")
+	}
+	return nil
+}
+
+func (m *machine) commands() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return strings.Join(m.applied, ",")
+}
+
+// testCluster runs nodes n1..nN over a MemoryNetwork
+type testCluster struct {
+	t        *testing.T
+	network  *MemoryNetwork
+	nodes    map[string]*Node
+	machines map[string]*machine
+}
+
+func newTestCluster(t *testing.T, size int, snapshotEvery uint64) *testCluster {
+	t.Helper()
+	c := &testCluster{t: t, network: NewMemoryNetwork(), nodes: make(map[string]*Node), machines: make(map[string]*machine)}
+	var members []*proto.RaftMember
+	for i := 1; i <= size; i++ {
+		members = append(members, &proto.RaftMember{Id: fmt.Sprintf("n%d", i)})
+	}
+	for _, member := range members {
+		m := &machine{}
+		node, err := New(Config{
+			ID:                member.Id,
+			Members:           members,
+			Transport:         c.network.Transport(member.Id),
+			Storage:           MemoryStorage{},
+			Apply:             m.apply,
+			Snapshot:          m.snapshot,
+			Restore:           m.restore,
+			SnapshotEvery:     snapshotEvery,
+			HeartbeatInterval: 10 * time.Millisecond,
+			ElectionTimeout:   100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.network.Register(node)
+		c.nodes[member.Id], c.machines[member.Id] = node, m
+	}
+	for _, node := range c.nodes {
+		node.Run()
+	}
+	t.Cleanup(func() {
+		for _, node := range c.nodes {
+			node.Stop()
+		}
+	})
+	return c
+}
+
+// leader waits until exactly one of the connected nodes leads and all of them agree on it
+func (c *testCluster) leader(except ...string) *Node {
+	c.t.Helper()
+	skip := make(map[string]bool)
+	for _, id := range except {
+		skip[id] = true
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var found *Node
+		agreed := true
+		leaders := 0
+		for id, node := range c.nodes {
+			if skip[id] {
+				continue
+			}
+			role, _, _ := node.Status()
+			if role == "leader" {
+				leaders++
+				found = node
+			}
+		}
+		if leaders == 1 {
+			for id, node := range c.nodes {
+				if _, _, leader := node.Status(); !skip[id] && leader != found.ID() {
+					agreed = false
+				}
+			}
+			if agreed {
+				return found
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.t.Fatal("no single leader was elected")
+	return nil
+}
+
+func (c *testCluster) propose(node *Node, command string) {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := node.Propose(ctx, []byte(command)); err != nil {
+		c.t.Fatalf("propose %s on %s: %v", command, node.ID(), err)
+	}
+}
+
+// applied waits until the node applied exactly these commands
+func (c *testCluster) applied(id, want string) {
+	c.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if c.machines[id].commands() == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.t.Fatalf("%s applied %q, want %q", id, c.machines[id].commands(), want)
+}
+
+func TestElection(t *testing.T) {
+	c := newTestCluster(t, 3, 0)
+	leader := c.leader()
+	_, term, _ := leader.Status()
+	for id, node := range c.nodes {
+		if _, other, _ := node.Status(); other != term {
+			t.Fatalf("%s is in term %d, the leader in %d", id, other, term)
+		}
+	}
+
+	//Proposals work through followers as well
+	for id, node := range c.nodes {
+		c.propose(node, id)
+	}
+	want := c.machines[leader.ID()].commands()
+	for id := range c.nodes {
+		c.applied(id, want)
+	}
+}
+
+func TestLeaderPartitionAndHeal(t *testing.T) {
+	c := newTestCluster(t, 3, 0)
+	old := c.leader()
+	c.propose(old, "a")
+
+	c.network.Disconnect(old.ID(), true)
+	// The cut off leader cannot commit anything
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	if err := old.Propose(ctx, []byte("lost")); err == nil {
+		t.Fatal("a leader without a majority committed a command")
+	}
+	cancel()
+
+	leader := c.leader(old.ID())
+	if leader == old {
+		t.Fatal("the cut off leader is still the leader")
+	}
+	c.propose(leader, "b")
+
+	c.network.Disconnect(old.ID(), false)
+	if c.leader() == old {
+		t.Fatal("the old leader took over again without the newest log")
+	}
+	for id := range c.nodes {
+		c.applied(id, "a,b")
+	}
+}
+
+func TestFollowerCatchesUp(t *testing.T) {
+	c := newTestCluster(t, 3, 0)
+	leader := c.leader()
+	var behind string
+	for id := range c.nodes {
+		if id != leader.ID() {
+			behind = id
+			break
+		}
+	}
+
+	c.network.Disconnect(behind, true)
+	var want []string
+	for i := 0; i < 10; i++ {
+		command := fmt.Sprintf("c%d", i)
+		c.propose(leader, command)
+		want = append(want, command)
+	}
+	c.network.Disconnect(behind, false)
+	c.applied(behind, strings.Join(want, ","))
+}
+
+func TestSnapshotInstall(t *testing.T) {
+	c := newTestCluster(t, 3, 5)
+	leader := c.leader()
+	var behind string
+	for id := range c.nodes {
+		if id != leader.ID() {
+			behind = id
+			break
+		}
+	}
+
+	c.network.Disconnect(behind, true)
+	var want []string
+	for i := 0; i < 20; i++ {
+		command := fmt.Sprintf("c%d", i)
+		c.propose(leader, command)
+		want = append(want, command)
+	}
+	// The leader compacted what the follower is missing, only a snapshot brings it back
+	leader.mutex.Lock()
+	compacted := leader.snapshot.Index
+	leader.mutex.Unlock()
+	if compacted == 0 {
+		t.Fatal("the leader did not compact its log")
+	}
+
+	c.network.Disconnect(behind, false)
+	c.applied(behind, strings.Join(want, ","))
+	c.propose(leader, "after")
+	c.applied(behind, strings.Join(append(want, "after"), ","))
+}
+
+func TestMayPropose(t *testing.T) {
+	node, err := New(Config{
+		ID:        "n1",
+		Members:   []*proto.RaftMember{{Id: "n1"}, {Id: "n2"}},
+		Transport: NewMemoryNetwork().Transport("n1"),
+		Storage:   MemoryStorage{},
+		Apply:     func([]byte) {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		caller string
+		req    *proto.ProposeRequest
+		ok     bool
+	}{
+		{"member proposes a command", "n2", &proto.ProposeRequest{Command: []byte("x")}, true},
+		{"member adds a node", "n2", &proto.ProposeRequest{Add: &proto.RaftMember{Id: "n3"}}, true},
+		{"member removes itself", "n2", &proto.ProposeRequest{Remove: "n2"}, true},
+		{"member removes another", "n2", &proto.ProposeRequest{Remove: "n1"}, false},
+		{"stranger asks to join", "n3", &proto.ProposeRequest{Add: &proto.RaftMember{Id: "n3"}}, true},
+		{"stranger adds someone else", "n3", &proto.ProposeRequest{Add: &proto.RaftMember{Id: "n4"}}, false},
+		{"stranger proposes a command", "n3", &proto.ProposeRequest{Command: []byte("x")}, false},
+		{"stranger joins with a command", "n3", &proto.ProposeRequest{Add: &proto.RaftMember{Id: "n3"}, Command: []byte("x")}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := node.mayPropose(test.caller, test.req)
+			if (err == nil) != test.ok {
+				t.Fatalf("mayPropose = %v, want ok=%v", err, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package raft
+
+import (
+	proto "ChitChat/grpc"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Storage keeps what a node must not forget across restarts: its term and vote,
+// the log and the latest snapshot
+type Storage interface {
+	// Load returns what was saved before, nils on a fresh start. The entries are the
+	// ones right after the snapshot
+	Load() (*proto.RaftState, *proto.RaftSnapshot, []*proto.RaftEntry, error)
+	SaveState(state *proto.RaftState) error
+	// Append adds entries to the end of the log
+	Append(entries []*proto.RaftEntry) error
+	// Rewrite replaces the whole log, after a conflict cut it short or a snapshot compacted it
+	Rewrite(entries []*proto.RaftEntry) error
+	SaveSnapshot(snapshot *proto.RaftSnapshot) error
+}
+
+// MemoryStorage forgets everything when the process exits, for in-process clusters
+type MemoryStorage struct{}
+
+func (MemoryStorage) Load() (*proto.RaftState, *proto.RaftSnapshot, []*proto.RaftEntry, error) {
+	return nil, nil, nil, nil
+}
+func (MemoryStorage) SaveState(*proto.RaftState) error       { return nil }
+func (MemoryStorage) Append([]*proto.RaftEntry) error        { return nil }
+func (MemoryStorage) Rewrite([]*proto.RaftEntry) error       { return nil }
+func (MemoryStorage) SaveSnapshot(*proto.RaftSnapshot) error { return nil }
+
+// FileStorage keeps a node in a directory: "state" and "snapshot" are replaced as a whole,
+// "log" is appended to with the same length-prefixed records as the chat history
+type FileStorage struct {
+	dir string
+	log *os.File
+}
+
+// OpenFileStorage creates the directory if needed
+func OpenFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	log, err := os.OpenFile(filepath.Join(dir, "log"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStorage{dir: dir, log: log}, nil
+}
+
+func (f *FileStorage) Load() (*proto.RaftState, *proto.RaftSnapshot, []*proto.RaftEntry, error) {
+	var state *proto.RaftState
+	if data, err := os.ReadFile(filepath.Join(f.dir, "state")); err == nil {
+		state = &proto.RaftState{}
+		if err := protobuf.Unmarshal(data, state); err != nil {
+			return nil, nil, nil, fmt.Errorf("state: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, err
+	}
+
+	var snapshot *proto.RaftSnapshot
+	if data, err := os.ReadFile(filepath.Join(f.dir, "snapshot")); err == nil {
+		snapshot = &proto.RaftSnapshot{}
+		if err := protobuf.Unmarshal(data, snapshot); err != nil {
+			return nil, nil, nil, fmt.Errorf("snapshot: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, err
+	}
+
+	var entries []*proto.RaftEntry
+	reader := bufio.NewReader(io.NewSectionReader(f.log, 0, 1<<62))
+	for {
+		size, err := binary.ReadUvarint(reader)
+		if err != nil {
+			break // end of the log, or a record cut short by a crash
+		}
+		record := make([]byte, size)
+		if _, err := io.ReadFull(reader, record); err != nil {
+			break
+		}
+		entry := &proto.RaftEntry{}
+		if err := protobuf.Unmarshal(record, entry); err != nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	entries = after(snapshot, entries)
+	// Drop a partial record at the end, later appends go after the last good one
+	if err := f.Rewrite(entries); err != nil {
+		return nil, nil, nil, err
+	}
+	return state, snapshot, entries, nil
+}
+
+// after drops the entries the snapshot already holds. The snapshot is saved before the
+// log is rewritten without them, a crash in between leaves them in the log
+func after(snapshot *proto.RaftSnapshot, entries []*proto.RaftEntry) []*proto.RaftEntry {
+	base := snapshot.GetIndex()
+	for len(entries) > 0 && entries[0].Index <= base {
+		entries = entries[1:]
+	}
+	if len(entries) > 0 && entries[0].Index != base+1 {
+		return nil // does not continue the snapshot, the leader sends it again
+	}
+	return entries
+}
+
+func (f *FileStorage) SaveState(state *proto.RaftState) error {
+	return f.replace("state", state)
+}
+
+func (f *FileStorage) Append(entries []*proto.RaftEntry) error {
+	var buf []byte
+	for _, entry := range entries {
+		record, err := protobuf.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(record)))
+		buf = append(buf, record...)
+	}
+	if _, err := f.log.Write(buf); err != nil {
+		return err
+	}
+	return f.log.Sync()
+}
+
+func (f *FileStorage) Rewrite(entries []*proto.RaftEntry) error {
+	path := filepath.Join(f.dir, "log")
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	old := f.log
+	f.log = tmp
+	if err := f.Append(entries); err != nil {
+		f.log = old
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(path+".tmp", path); err != nil {
+		f.log = old
+		return err
+	}
+	old.Close()
+
+	f.log, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0644)
+	return err
+}
+
+func (f *FileStorage) SaveSnapshot(snapshot *proto.RaftSnapshot) error {
+	return f.replace("snapshot", snapshot)
+}
+
+// Close closes the log file
+func (f *FileStorage) Close() error {
+	return f.log.Close()
+}
+
+// replace writes a file next to the old one and renames it over, so a crash leaves one or the other
+func (f *FileStorage) replace(name string, message protobuf.Message) error {
+	data, err := protobuf.Marshal(message)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(f.dir, name)
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
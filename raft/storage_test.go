@@ -0,0 +1,66 @@
+package raft
+
+import (
+	proto "ChitChat/grpc"
+	"testing"
+)
+
+func TestFileStorageDropsEntriesTheSnapshotHolds(t *testing.T) {
+	entries := func(from, to uint64) []*proto.RaftEntry {
+		var list []*proto.RaftEntry
+		for i := from; i <= to; i++ {
+			list = append(list, &proto.RaftEntry{Index: i, Term: 1})
+		}
+		return list
+	}
+
+	tests := []struct {
+		name     string
+		snapshot uint64
+		log      []*proto.RaftEntry
+		first    uint64 // index of the first entry Load returns, 0 for none
+		count    int
+	}{
+		{"no snapshot", 0, entries(1, 5), 1, 5},
+		{"log already compacted", 3, entries(4, 6), 4, 3},
+		{"crash before the log was rewritten", 3, entries(1, 6), 4, 3},
+		{"snapshot holds the whole log", 8, entries(1, 6), 0, 0},
+		{"log does not continue the snapshot", 3, entries(6, 8), 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f, err := OpenFileStorage(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Append(test.log); err != nil {
+				t.Fatal(err)
+			}
+			if test.snapshot > 0 {
+				if err := f.SaveSnapshot(&proto.RaftSnapshot{Index: test.snapshot, Term: 1}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			f.Close()
+
+			for round := 0; round < 2; round++ { // the second load reads what the first one rewrote
+				f, err := OpenFileStorage(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _, loaded, err := f.Load()
+				f.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(loaded) != test.count {
+					t.Fatalf("loaded %d entries, want %d", len(loaded), test.count)
+				}
+				if test.count > 0 && loaded[0].Index != test.first {
+					t.Fatalf("first entry has index %d, want %d", loaded[0].Index, test.first)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package raft
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// errUnreachable is what the in-memory network answers for a disconnected or unknown node
+var errUnreachable = errors.New("raft: node unreachable")
+
+// Transport carries the messages between nodes
+type Transport interface {
+	RequestVote(ctx context.Context, peer *proto.RaftMember, req *proto.VoteRequest) (*proto.VoteResponse, error)
+	AppendEntries(ctx context.Context, peer *proto.RaftMember, req *proto.AppendRequest) (*proto.AppendResponse, error)
+	InstallSnapshot(ctx context.Context, peer *proto.RaftMember, req *proto.SnapshotRequest) (*proto.SnapshotResponse, error)
+	Propose(ctx context.Context, peer *proto.RaftMember, req *proto.ProposeRequest) (*proto.ProposeResponse, error)
+}
+
+// MemoryNetwork connects nodes in one process, e.g. for tests or a local cluster.
+// Nodes can be cut off to simulate crashes and partitions
+type MemoryNetwork struct {
+	mutex        sync.Mutex
+	nodes        map[string]*Node
+	disconnected map[string]bool
+}
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		nodes:        make(map[string]*Node),
+		disconnected: make(map[string]bool),
+	}
+}
+
+// Register makes a node reachable under its ID
+func (m *MemoryNetwork) Register(node *Node) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nodes[node.ID()] = node
+}
+
+// Disconnect cuts a node off from all others, or connects it again
+func (m *MemoryNetwork) Disconnect(id string, disconnected bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.disconnected[id] = disconnected
+}
+
+// Transport returns the transport the node with this ID sends through
+func (m *MemoryNetwork) Transport(id string) Transport {
+	return &memoryTransport{network: m, from: id}
+}
+
+// reach returns the peer if both ends are connected
+func (m *MemoryNetwork) reach(from, to string) (*Node, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	node, ok := m.nodes[to]
+	if !ok || m.disconnected[from] || m.disconnected[to] {
+		return nil, errUnreachable
+	}
+	return node, nil
+}
+
+type memoryTransport struct {
+	network *MemoryNetwork
+	from    string
+}
+
+func (t *memoryTransport) RequestVote(ctx context.Context, peer *proto.RaftMember, req *proto.VoteRequest) (*proto.VoteResponse, error) {
+	node, err := t.network.reach(t.from, peer.Id)
+	if err != nil {
+		return nil, err
+	}
+	return node.HandleVote(req), nil
+}
+
+func (t *memoryTransport) AppendEntries(ctx context.Context, peer *proto.RaftMember, req *proto.AppendRequest) (*proto.AppendResponse, error) {
+	node, err := t.network.reach(t.from, peer.Id)
+	if err != nil {
+		return nil, err
+	}
+	return node.HandleAppend(req), nil
+}
+
+func (t *memoryTransport) InstallSnapshot(ctx context.Context, peer *proto.RaftMember, req *proto.SnapshotRequest) (*proto.SnapshotResponse, error) {
+	node, err := t.network.reach(t.from, peer.Id)
+	if err != nil {
+		return nil, err
+	}
+	return node.HandleSnapshot(req), nil
+}
+
+func (t *memoryTransport) Propose(ctx context.Context, peer *proto.RaftMember, req *proto.ProposeRequest) (*proto.ProposeResponse, error) {
+	node, err := t.network.reach(t.from, peer.Id)
+	if err != nil {
+		return nil, err
+	}
+	return node.HandlePropose(ctx, req), nil
+}
+
+// GRPCTransport sends through the Raft gRPC service, dialing every peer address once
+type GRPCTransport struct {
+	creds   credentials.TransportCredentials
+	mutex   sync.Mutex
+	clients map[string]proto.RaftClient
+}
+
+func NewGRPCTransport(creds credentials.TransportCredentials) *GRPCTransport {
+	return &GRPCTransport{creds: creds, clients: make(map[string]proto.RaftClient)}
+}
+
+func (t *GRPCTransport) client(peer *proto.RaftMember) (proto.RaftClient, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if client, ok := t.clients[peer.Addr]; ok {
+		return client, nil
+	}
+	//Reconnect quickly, a restarted peer that hears nothing would start an election
+	reconnect := backoff.DefaultConfig
+	reconnect.MaxDelay = time.Second
+	conn, err := grpc.NewClient(peer.Addr,
+		grpc.WithTransportCredentials(t.creds),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: reconnect}))
+	if err != nil {
+		return nil, err
+	}
+	client := proto.NewRaftClient(conn)
+	t.clients[peer.Addr] = client
+	return client, nil
+}
+
+func (t *GRPCTransport) RequestVote(ctx context.Context, peer *proto.RaftMember, req *proto.VoteRequest) (*proto.VoteResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.RequestVote(ctx, req)
+}
+
+func (t *GRPCTransport) AppendEntries(ctx context.Context, peer *proto.RaftMember, req *proto.AppendRequest) (*proto.AppendResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.AppendEntries(ctx, req)
+}
+
+func (t *GRPCTransport) InstallSnapshot(ctx context.Context, peer *proto.RaftMember, req *proto.SnapshotRequest) (*proto.SnapshotResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.InstallSnapshot(ctx, req)
+}
+
+func (t *GRPCTransport) Propose(ctx context.Context, peer *proto.RaftMember, req *proto.ProposeRequest) (*proto.ProposeResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.Propose(ctx, req)
+}
+
+// Service serves a node over gRPC. Calls that claim to come from another node than the
+// one making them are turned down, and so are proposals the caller may not make
+type Service struct {
+	proto.UnimplementedRaftServer
+	node      *Node
+	caller    func(ctx context.Context) string
+	authorize func(caller string, req *proto.ProposeRequest) error
+}
+
+// NewService serves node. caller returns the ID of the node making a call as the transport
+// proved it, empty if it cannot tell (then nothing is checked). authorize may turn down
+// the commands of a proposal, it can be nil
+func NewService(node *Node, caller func(ctx context.Context) string, authorize func(caller string, req *proto.ProposeRequest) error) *Service {
+	return &Service{node: node, caller: caller, authorize: authorize}
+}
+
+// impersonating tells whether the call comes from another node than claimed
+func (s *Service) impersonating(ctx context.Context, claimed string) error {
+	if s.caller == nil {
+		return nil
+	}
+	if caller := s.caller(ctx); caller != "" && caller != claimed {
+		return status.Errorf(codes.PermissionDenied, "raft: %s may not speak for %s", caller, claimed)
+	}
+	return nil
+}
+
+func (s *Service) RequestVote(ctx context.Context, req *proto.VoteRequest) (*proto.VoteResponse, error) {
+	if err := s.impersonating(ctx, req.Candidate); err != nil {
+		return nil, err
+	}
+	return s.node.HandleVote(req), nil
+}
+
+func (s *Service) AppendEntries(ctx context.Context, req *proto.AppendRequest) (*proto.AppendResponse, error) {
+	if err := s.impersonating(ctx, req.Leader); err != nil {
+		return nil, err
+	}
+	return s.node.HandleAppend(req), nil
+}
+
+func (s *Service) InstallSnapshot(ctx context.Context, req *proto.SnapshotRequest) (*proto.SnapshotResponse, error) {
+	if err := s.impersonating(ctx, req.Leader); err != nil {
+		return nil, err
+	}
+	return s.node.HandleSnapshot(req), nil
+}
+
+// Propose takes a proposal from another node. Members may pass on anything, a node that
+// is not a member yet may only ask to be added itself, and a node may only remove itself
+func (s *Service) Propose(ctx context.Context, req *proto.ProposeRequest) (*proto.ProposeResponse, error) {
+	if s.caller != nil {
+		if caller := s.caller(ctx); caller != "" {
+			if err := s.node.mayPropose(caller, req); err != nil {
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			if s.authorize != nil && len(req.Command) > 0 {
+				if err := s.authorize(caller, req); err != nil {
+					return nil, status.Error(codes.PermissionDenied, err.Error())
+				}
+			}
+		}
+	}
+	return s.node.HandlePropose(ctx, req), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tokenHeader carries the session token: Subscribe hands it out in its response header,
+// Publish and Leave must send it back in their request metadata
+const tokenHeader = "chitchat-token"
+
+// newToken returns a random, unguessable session token
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// authorize checks that the call carries the token of one of clientID's sessions in the room,
+// so nobody can publish as or evict someone else. Must be called with s.mutex held
+func authorize(ctx context.Context, r *room, clientID string) (*subscriber, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md.Get(tokenHeader)
+	if len(tokens) == 0 || tokens[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "session token required, subscribe first")
+	}
+
+	for _, sub := range r.subscribers {
+		if sub.token == tokens[0] {
+			if sub.id != clientID {
+				return nil, status.Errorf(codes.PermissionDenied, "token does not belong to %s", clientID)
+			}
+			return sub, nil
+		}
+	}
+	return nil, status.Errorf(codes.PermissionDenied, "token is not valid for room %s", r.name)
+}
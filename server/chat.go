@@ -0,0 +1,144 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chatStream lets the subscribers sender goroutine and the ACKs of the event loop
+// share one Chat stream, gRPC does not allow two goroutines to Send at once
+type chatStream struct {
+	proto.ChitChat_ChatServer
+	mutex sync.Mutex
+}
+
+func (c *chatStream) Send(broadcast *proto.BroadCast) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ChitChat_ChatServer.Send(broadcast)
+}
+
+// Chat carries join, publish, typing and leave of one session over a single stream.
+// The first event must be a join, after that the stream works like Subscribe and
+// every event is answered with an ACK. The stream is the session, so no token is checked
+func (s *ChitChatServer) Chat(stream proto.ChitChat_ChatServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	join := first.GetJoin()
+	if join == nil {
+		return status.Error(codes.FailedPrecondition, "the first event on a Chat stream must be a join")
+	}
+
+	out := &chatStream{ChitChat_ChatServer: stream}
+	r, sub, err := s.attach(stream.Context(), join, out)
+	if err != nil {
+		return err
+	}
+	//Nothing is sent from the queue yet, so the ACK is the first broadcast after the join.
+	//If the stream is already broken serve finds out on its own
+	_ = out.Send(ack(first.GetSeq(), nil))
+
+	events := make(chan error, 1)
+	go func() { events <- s.chatEvents(r, sub, stream, out) }()
+	s.serve(stream.Context(), r, sub, events)
+	return nil
+}
+
+// chatEvents handles what the client sends after joining, until it leaves or the stream ends
+func (s *ChitChatServer) chatEvents(r *room, sub *subscriber, stream proto.ChitChat_ChatServer, out *chatStream) error {
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var result error
+		messageID := ""
+		switch e := event.GetEvent().(type) {
+		case *proto.ClientEvent_Publish:
+			messageID, result = s.chatPublish(r, sub, e.Publish)
+		case *proto.ClientEvent_Leave:
+			//ACK first, leaving closes the stream
+			if err := out.Send(ack(event.GetSeq(), nil)); err != nil {
+				return err
+			}
+			s.chatLeave(r, sub, e.Leave)
+			return nil
+		case *proto.ClientEvent_Typing:
+			result = s.chatTyping(r, sub, e.Typing)
+		case *proto.ClientEvent_Join:
+			result = status.Error(codes.FailedPrecondition, "already joined, open a new stream to change room")
+		default:
+			result = status.Error(codes.InvalidArgument, "empty event")
+		}
+		reply := ack(event.GetSeq(), result)
+		reply.MessageId = messageID
+		if err := out.Send(reply); err != nil {
+			return err
+		}
+	}
+}
+
+// chatPublish publishes a message from the session of a Chat stream and returns its message ID
+func (s *ChitChatServer) chatPublish(r *room, sub *subscriber, req *proto.PublishRequest) (string, error) {
+	if req.GetClientId() == "" {
+		req.ClientId = sub.id
+	}
+	if req.GetClientId() != sub.id {
+		return "", status.Errorf(codes.PermissionDenied, "this stream belongs to %s", sub.id)
+	}
+	if roomName(req.GetRoom()) != r.name {
+		return "", status.Errorf(codes.InvalidArgument, "this stream is joined to room %s", r.name)
+	}
+
+	var response *proto.PublishResponse
+	err := s.update(func() (*proto.ChatCommand, error) {
+		if r.subscribers[sub.session] != sub {
+			return nil, status.Errorf(codes.FailedPrecondition, "session %s is no longer in room %s", sub.session, r.name)
+		}
+		var command *proto.ChatCommand
+		var err error
+		response, command, err = s.publish(r, req)
+		return command, err
+	})
+	if err != nil {
+		return "", err
+	}
+	if !response.GetAck() {
+		return "", &rejection{code: response.GetErrorCode(), reason: response.GetError()}
+	}
+	return response.GetMessageId(), nil
+}
+
+// chatLeave ends the session of a Chat stream, the participants other sessions stay
+func (s *ChitChatServer) chatLeave(r *room, sub *subscriber, req *proto.LeaveRequest) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if r.subscribers[sub.session] != sub {
+		return
+	}
+	s.leave(r, sub.id, map[string]*subscriber{sub.session: sub}, req.GetTimestamp())
+}
+
+// ack answers the ClientEvent with the given seq, err is nil if it was accepted
+func ack(seq int64, err error) *proto.BroadCast {
+	broadcast := &proto.BroadCast{Type: proto.BroadCast_ACK, AckSeq: seq}
+	var rejected *rejection
+	if errors.As(err, &rejected) {
+		broadcast.Error = rejected.reason
+		broadcast.ErrorCode = rejected.code
+	} else if err != nil {
+		broadcast.Error = status.Convert(err).Message()
+	}
+	return broadcast
+}
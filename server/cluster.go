@@ -0,0 +1,468 @@
+package main
+
+import (
+	"ChitChat/clock"
+	proto "ChitChat/grpc"
+	"ChitChat/raft"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	commitTimeout = 5 * time.Second // how long a request waits for the cluster to commit it
+	commitRetry   = 100 * time.Millisecond
+)
+
+// In cluster mode every JOIN, CHAT, DIRECT, LEAVE and new room goes through the Raft log
+// first. Each node applies the committed commands in the same order, stamps them with the
+// same Lamport time and delivers them to its own subscribers
+
+// commit appends a command to the cluster log and waits until this node applied it.
+// Applying takes s.mutex, so it must be called without it. A nil command is nothing to commit
+func (s *ChitChatServer) commit(command *proto.ChatCommand) error {
+	if command == nil {
+		return nil
+	}
+	command.Node = s.node
+	command.Proposal = strconv.FormatInt(s.proposed.Add(1), 10)
+	data, err := protobuf.Marshal(command)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to encode command: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+	for {
+		err := s.cluster.Propose(ctx, data)
+		if err == nil {
+			return nil
+		}
+		// The leader may have committed it before the answer got lost. Trying again is
+		// fine, the retry keeps the proposal number and apply skips it the second time
+		if !errors.Is(err, raft.ErrNoLeader) && status.Code(err) != codes.Unavailable {
+			return status.Errorf(codes.Unavailable, "cluster did not commit: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return status.Error(codes.Unavailable, "cluster has no leader, try again")
+		case <-time.After(commitRetry):
+		}
+	}
+}
+
+// commitAsync is commit for callers that cannot wait, e.g. while evicting in a broadcast
+func (s *ChitChatServer) commitAsync(command *proto.ChatCommand) {
+	go func() {
+		if err := s.commit(command); err != nil {
+			log.Printf("Server CLUSTER_ERROR: %v", status.Convert(err).Message())
+		}
+	}()
+}
+
+// update runs change with s.mutex held and commits the command it returns once the lock
+// is released. Whatever change checked may be different by the time the command is
+// applied, so applyCommand checks it again
+func (s *ChitChatServer) update(change func() (*proto.ChatCommand, error)) error {
+	s.mutex.Lock()
+	command, err := change()
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.commit(command)
+}
+
+// eventCommand wraps a JOIN, LEAVE, message or change to one for the cluster log
+func eventCommand(event *proto.BroadCast) *proto.ChatCommand {
+	return &proto.ChatCommand{Command: &proto.ChatCommand_Event{Event: event}}
+}
+
+// applyCommand runs one committed command of the cluster log
+func (s *ChitChatServer) applyCommand(data []byte) {
+	command := &proto.ChatCommand{}
+	if err := protobuf.Unmarshal(data, command); err != nil {
+		log.Printf("Server CLUSTER_ERROR: skipping a command that does not decode: %v", err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if command.GetProposal() != "" && !s.proposals.add(command.GetNode(), command.GetProposal()) {
+		log.Printf("Server CLUSTER: proposal %s of %s was committed twice, applied once", command.GetProposal(), command.GetNode())
+		return
+	}
+	if name := command.GetCreateRoom(); name != "" {
+		if _, exists := s.rooms[name]; !exists {
+			s.rooms[name] = newRoom(name, 0, s.vectorMode)
+			log.Printf("Server ROOM_CREATED: %s", name)
+		}
+		return
+	}
+	if id := command.GetRestarted(); id != "" {
+		s.forgetRuns(id, command.GetNode())
+		return
+	}
+	if receipt := command.GetReceipt(); receipt != nil {
+		if r, ok := s.rooms[receipt.GetRoom()]; ok {
+			s.acknowledge(r, receipt)
+		}
+		return
+	}
+
+	event := command.GetEvent()
+	r, ok := s.rooms[roomOf(event)]
+	if !ok {
+		return
+	}
+	clientID := event.GetClientId()
+	switch event.GetType() {
+	case proto.BroadCast_JOIN:
+		//Only the first node a participant shows up on is a JOIN
+		nodes := r.present[clientID]
+		if nodes == nil {
+			nodes = make(map[string]bool)
+			r.present[clientID] = nodes
+		}
+		nodes[command.GetNode()] = true
+		if len(nodes) > 1 {
+			return
+		}
+		event.Timestamp = r.clock.Merge(event.GetTimestamp())
+		s.emit(r, event)
+		log.Printf("Participant %s joined Chit Chat room %s at logical time %d", clientID, r.name, event.Timestamp)
+
+	case proto.BroadCast_LEAVE:
+		if !r.present[clientID][command.GetNode()] {
+			return
+		}
+		currentTime := r.clock.Merge(event.GetTimestamp())
+		delete(r.present[clientID], command.GetNode())
+		if len(r.present[clientID]) > 0 {
+			return
+		}
+		delete(r.present, clientID)
+		event.Timestamp = currentTime
+		s.emit(r, event)
+		log.Printf("Participant %s left Chit Chat room %s at logical time %d", clientID, r.name, currentTime)
+
+	case proto.BroadCast_CHAT:
+		// Two nodes may both have let a retry, or the same ID of someone else, through.
+		// Only the first one in the log counts
+		if target, _ := s.message(r, event.GetMessageId()); target != nil || !s.dedup.add(clientID, event.GetMessageId()) {
+			return
+		}
+		event.Timestamp = r.clock.Merge(event.GetTimestamp())
+		event.Vector = senderVector(r, clientID, event.GetVector())
+		s.emit(r, event)
+		log.Printf("Server Publish committed: room=%s from=%s logical_time=%d content=%q", r.name, clientID, event.Timestamp, event.Message)
+
+	case proto.BroadCast_EDIT, proto.BroadCast_DELETE, proto.BroadCast_REACTION:
+		// Checked on the node that proposed it, but a delete or the same reaction may have come first
+		target, deleted := s.message(r, event.GetMessageId())
+		if target == nil || deleted {
+			return
+		}
+		if event.GetType() == proto.BroadCast_REACTION && !s.tally(r, event) {
+			return
+		}
+		event.Timestamp = r.clock.Merge(event.GetTimestamp())
+		s.deliverAmend(r, target, event)
+
+	case proto.BroadCast_PRESENCE:
+		if len(r.present[clientID]) == 0 {
+			return
+		}
+		event.Timestamp = r.clock.Merge(event.GetTimestamp())
+		s.emit(r, event)
+		log.Printf("Server PRESENCE: room=%s %s is %s logical_time=%d", r.name, clientID, event.Status, event.Timestamp)
+
+	case proto.BroadCast_DIRECT:
+		if target, _ := s.message(r, event.GetMessageId()); target != nil || !s.dedup.add(clientID, event.GetMessageId()) {
+			return
+		}
+		targets := r.sessionsOf(event.GetRecipient())
+		for session, sub := range r.sessionsOf(clientID) {
+			targets[session] = sub
+		}
+		event.Timestamp = r.clock.Merge(event.GetTimestamp())
+		s.record(r, event)
+		s.broadcast(r, event, targets)
+		log.Printf("Server Direct committed: room=%s from=%s to=%s logical_time=%d", r.name, clientID, event.Recipient, event.Timestamp)
+	}
+}
+
+// forgetRuns drops the participants of earlier runs of a node that came back, it cannot
+// tell whether they are still around. Goes through rooms and participants sorted, so every
+// node hands out the same timestamps. Must be called with s.mutex held
+func (s *ChitChatServer) forgetRuns(id, current string) {
+	names := make([]string, 0, len(s.rooms))
+	for name := range s.rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := s.rooms[name]
+		clientIDs := make([]string, 0, len(r.present))
+		for clientID := range r.present {
+			clientIDs = append(clientIDs, clientID)
+		}
+		sort.Strings(clientIDs)
+		for _, clientID := range clientIDs {
+			nodes := r.present[clientID]
+			for node := range nodes {
+				if nodeID, _, _ := strings.Cut(node, "@"); nodeID == id && node != current {
+					delete(nodes, node)
+				}
+			}
+			if len(nodes) > 0 {
+				continue
+			}
+			delete(r.present, clientID)
+			leave := &proto.BroadCast{
+				Type:      proto.BroadCast_LEAVE,
+				ClientId:  clientID,
+				Message:   "disconnected: server restarted",
+				Timestamp: r.clock.Tick(),
+			}
+			s.emit(r, leave)
+			log.Printf("Participant %s disconnected from room %s (server %s restarted) at logical time %d", clientID, r.name, id, leave.Timestamp)
+		}
+	}
+}
+
+// snapshotChat captures the history and every rooms clocks and participants for log compaction
+func (s *ChitChatServer) snapshotChat() ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := &proto.ChatSnapshot{Events: s.history.events}
+	for _, r := range s.rooms {
+		state := &proto.RoomState{Name: r.name, Clock: r.clock.Now()}
+		if r.vector != nil {
+			state.Vector = r.vector.Snapshot()
+		}
+		for clientID, nodes := range r.present {
+			for node := range nodes {
+				state.Present = append(state.Present, &proto.Presence{ClientId: clientID, Node: node})
+			}
+		}
+		for messageID, rc := range r.receipts {
+			state.Receipts = append(state.Receipts, &proto.ReceiptState{
+				MessageId: messageID,
+				Author:    rc.author,
+				Delivered: names(rc.delivered),
+				Read:      names(rc.read),
+			})
+		}
+		snapshot.Rooms = append(snapshot.Rooms, state)
+	}
+	for _, key := range s.proposals.order {
+		snapshot.Proposals = append(snapshot.Proposals, &proto.Proposal{Node: key.clientID, Proposal: key.messageID})
+	}
+	return protobuf.Marshal(snapshot)
+}
+
+// restoreChat replaces the chat state with a snapshot from the cluster. Local
+// subscribers stay in their rooms
+func (s *ChitChatServer) restoreChat(data []byte) error {
+	snapshot := &proto.ChatSnapshot{}
+	if err := protobuf.Unmarshal(data, snapshot); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.history.events = snapshot.GetEvents()
+	s.dedup.load(s.history.events)
+	s.proposals = newDedupWindow(s.proposals.size)
+	for _, proposal := range snapshot.GetProposals() {
+		s.proposals.add(proposal.GetNode(), proposal.GetProposal())
+	}
+	for _, state := range snapshot.GetRooms() {
+		r, ok := s.rooms[state.GetName()]
+		if !ok {
+			r = newRoom(state.GetName(), 0, s.vectorMode)
+			s.rooms[r.name] = r
+		}
+		r.clock = clock.NewLamport(state.GetClock())
+		if r.vector != nil {
+			r.vector = clock.NewVector()
+			r.vector.Merge(state.GetVector())
+		}
+		r.last = 0
+		r.messages = make(map[string]*posted)
+		r.present = make(map[string]map[string]bool)
+		for _, presence := range state.GetPresent() {
+			if r.present[presence.ClientId] == nil {
+				r.present[presence.ClientId] = make(map[string]bool)
+			}
+			r.present[presence.ClientId][presence.Node] = true
+		}
+		r.receipts = make(map[string]*receipt)
+		for _, state := range state.GetReceipts() {
+			rc := &receipt{author: state.GetAuthor(), delivered: make(map[string]bool), read: make(map[string]bool)}
+			for _, clientID := range state.GetDelivered() {
+				rc.delivered[clientID] = true
+			}
+			for _, clientID := range state.GetRead() {
+				rc.read[clientID] = true
+			}
+			r.receipts[state.GetMessageId()] = rc
+		}
+	}
+	for _, event := range s.history.events {
+		r, ok := s.rooms[roomOf(event)]
+		if !ok {
+			continue
+		}
+		if event.GetRecipient() == "" {
+			r.last = event.GetTimestamp()
+		}
+		r.index(event)
+	}
+	log.Printf("Server CLUSTER: restored %d broadcasts and %d rooms from a snapshot", len(snapshot.GetEvents()), len(snapshot.GetRooms()))
+	return nil
+}
+
+// parseMembers reads "id=host:port,id=host:port"
+func parseMembers(list string) ([]*proto.RaftMember, error) {
+	var members []*proto.RaftMember
+	for _, entry := range strings.Split(list, ",") {
+		id, addr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || id == "" || addr == "" {
+			return nil, fmt.Errorf("bad cluster member %q, use id=host:port", entry)
+		}
+		members = append(members, &proto.RaftMember{Id: id, Addr: addr})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Id < members[j].Id })
+	return members, nil
+}
+
+// startCluster makes this server a node of a Raft cluster. members is the cluster to
+// bootstrap, or empty when the node is added to a running cluster through join
+func (s *ChitChatServer) startCluster(id string, self *proto.RaftMember, members []*proto.RaftMember, join, dir string, snapshotEvery uint64, creds credentials.TransportCredentials) (*raft.Node, error) {
+	var storage raft.Storage = raft.MemoryStorage{}
+	if dir != "" {
+		files, err := raft.OpenFileStorage(dir)
+		if err != nil {
+			return nil, err
+		}
+		storage = files
+	}
+	transport := raft.NewGRPCTransport(creds)
+	s.node = fmt.Sprintf("%s@%d", id, time.Now().UnixNano())
+
+	node, err := raft.New(raft.Config{
+		ID:            id,
+		Members:       members,
+		Transport:     transport,
+		Storage:       storage,
+		Apply:         s.applyCommand,
+		Snapshot:      s.snapshotChat,
+		Restore:       s.restoreChat,
+		SnapshotEvery: snapshotEvery,
+	})
+	if err != nil {
+		return nil, err
+	}
+	//Applying commands needs s.cluster, so it is set before the node runs
+	s.cluster = node
+	node.Run()
+
+	//Whoever was on an earlier run of this node lost their session with it
+	s.commitAsync(&proto.ChatCommand{Command: &proto.ChatCommand_Restarted{Restarted: id}})
+	if join != "" {
+		go func() {
+			//The node we ask passes it on to the leader
+			for {
+				ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+				response, err := transport.Propose(ctx, &proto.RaftMember{Addr: join}, &proto.ProposeRequest{Add: self})
+				cancel()
+				if err == nil && (response.GetError() == "" || strings.Contains(response.GetError(), "already a member")) {
+					log.Printf("Server CLUSTER: joined the cluster through %s", join)
+					return
+				}
+				if err == nil {
+					err = errors.New(response.GetError())
+				}
+				log.Printf("Server CLUSTER: joining through %s: %v", join, err)
+				time.Sleep(time.Second)
+			}
+		}()
+	}
+	return node, nil
+}
+
+// leaveCluster takes this node out of the cluster before it shuts down
+func (s *ChitChatServer) leaveCluster() {
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+	if err := s.cluster.RemoveMember(ctx, s.cluster.ID()); err != nil {
+		log.Printf("Server CLUSTER: failed to leave the cluster: %v", err)
+		return
+	}
+	log.Printf("Server CLUSTER: left the cluster")
+}
+
+// publishCluster returns the command for a validated message, a DIRECT only to someone who
+// is present on any of the nodes. Must be called with s.mutex held
+func (s *ChitChatServer) publishCluster(r *room, req *proto.PublishRequest) (*proto.PublishResponse, *proto.ChatCommand, error) {
+	event := &proto.BroadCast{
+		Type:      proto.BroadCast_CHAT,
+		ClientId:  req.GetClientId(),
+		Message:   req.GetText(),
+		Timestamp: req.GetTimestamp(),
+		Vector:    req.GetVector(),
+		Room:      r.name,
+		MessageId: req.GetMessageId(),
+		ParentId:  req.GetParentId(),
+	}
+	if recipient := req.GetRecipient(); recipient != "" {
+		if len(r.present[recipient]) == 0 {
+			return nil, nil, status.Errorf(codes.NotFound, "%s is not subscribed to room %s", recipient, r.name)
+		}
+		event.Type = proto.BroadCast_DIRECT
+		event.Recipient = recipient
+	}
+	return &proto.PublishResponse{Ack: true, MessageId: event.MessageId}, eventCommand(event), nil
+}
+
+// clusterMembers works out this node and the cluster to start with from the flags.
+// A node that joins starts without members, the cluster adds it
+func clusterMembers(id, peers, join, addr string, port int) (*proto.RaftMember, []*proto.RaftMember, error) {
+	if (peers == "") == (join == "") {
+		return nil, nil, errors.New("cluster mode needs exactly one of -raft-peers and -raft-join")
+	}
+	self := &proto.RaftMember{Id: id, Addr: addr}
+	if join != "" {
+		if self.Addr == "" {
+			self.Addr = fmt.Sprintf("localhost:%d", port)
+		}
+		return self, nil, nil
+	}
+
+	members, err := parseMembers(peers)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, member := range members {
+		if member.Id == id {
+			if self.Addr == "" {
+				self.Addr = member.Addr
+			}
+			return self, members, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("-raft-peers does not list this node %s", id)
+}
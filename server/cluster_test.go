@@ -0,0 +1,113 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"slices"
+	"testing"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func TestApplyCommandSkipsRetriedProposals(t *testing.T) {
+	join := func(proposal string) []byte {
+		data, err := protobuf.Marshal(&proto.ChatCommand{
+			Command:  &proto.ChatCommand_Event{Event: &proto.BroadCast{Type: proto.BroadCast_JOIN, ClientId: "alice", Room: defaultRoom, Timestamp: 1}},
+			Node:     "n1@1",
+			Proposal: proposal,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	leave := func(proposal string) []byte {
+		data, err := protobuf.Marshal(&proto.ChatCommand{
+			Command:  &proto.ChatCommand_Event{Event: &proto.BroadCast{Type: proto.BroadCast_LEAVE, ClientId: "alice", Room: defaultRoom, Timestamp: 2}},
+			Node:     "n1@1",
+			Proposal: proposal,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name     string
+		commands [][]byte
+		events   int
+	}{
+		{"once", [][]byte{join("1")}, 1},
+		{"retry committed twice", [][]byte{join("1"), join("1")}, 1},
+		{"join, leave and the join again", [][]byte{join("1"), leave("2"), join("3")}, 3},
+		{"retried leave", [][]byte{join("1"), leave("2"), join("3"), leave("2")}, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t)
+			for _, command := range test.commands {
+				s.applyCommand(command)
+			}
+			if got := len(s.history.events); got != test.events {
+				t.Fatalf("history has %d events, want %d", got, test.events)
+			}
+		})
+	}
+}
+
+func TestSnapshotKeepsAppliedProposals(t *testing.T) {
+	command, err := protobuf.Marshal(&proto.ChatCommand{
+		Command:  &proto.ChatCommand_Event{Event: &proto.BroadCast{Type: proto.BroadCast_JOIN, ClientId: "alice", Room: defaultRoom, Timestamp: 1}},
+		Node:     "n1@1",
+		Proposal: "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t)
+	s.applyCommand(command)
+	snapshot, err := s.snapshotChat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A node that caught up through the snapshot must skip the retry like everyone else
+	restored := newTestServer(t)
+	if err := restored.restoreChat(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored.applyCommand(command)
+	if got := len(restored.history.events); got != 1 {
+		t.Fatalf("history has %d events after the retry, want 1", got)
+	}
+}
+
+func TestSnapshotKeepsReceipts(t *testing.T) {
+	s := newTestServer(t)
+	r := s.rooms[defaultRoom]
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "hi", MessageId: "m1"})
+	s.acknowledge(r, &proto.ReceiptRequest{ClientId: "bob", Room: defaultRoom, Read: []string{"m1"}})
+	s.acknowledge(r, &proto.ReceiptRequest{ClientId: "carol", Room: defaultRoom, Delivered: []string{"m1"}})
+	snapshot, err := s.snapshotChat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestServer(t)
+	if err := restored.restoreChat(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	rc, ok := restored.rooms[defaultRoom].receipts["m1"]
+	if !ok {
+		t.Fatal("the receipts of m1 are gone after the restore")
+	}
+	if got := names(rc.delivered); !slices.Equal(got, []string{"bob", "carol"}) {
+		t.Fatalf("delivered to %v, want [bob carol]", got)
+	}
+	if got := names(rc.read); !slices.Equal(got, []string{"bob"}) {
+		t.Fatalf("read by %v, want [bob]", got)
+	}
+	if rc.author != "alice" {
+		t.Fatalf("author %q, want alice", rc.author)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// serverConfig is the file given with -config. Whatever it leaves out keeps its default
+type serverConfig struct {
+	Validation validationConfig `json:"validation"`
+}
+
+// validationConfig picks the validation steps and their order. Steps are
+// utf8, strip, not_empty, max_runes, max_bytes and blocked_words
+type validationConfig struct {
+	Steps        []string `json:"steps"`
+	MaxRunes     int      `json:"max_runes"`
+	MaxBytes     int      `json:"max_bytes"`
+	BlockedWords []string `json:"blocked_words"`
+}
+
+func defaultConfig() serverConfig {
+	return serverConfig{Validation: validationConfig{
+		Steps:    []string{"utf8", "strip", "not_empty", "max_runes", "max_bytes", "blocked_words"},
+		MaxRunes: 128,
+		MaxBytes: 1024,
+	}}
+}
+
+// loadConfig reads the config file over the defaults, an empty path keeps the defaults
+func loadConfig(path string) (serverConfig, error) {
+	config := defaultConfig()
+	if path == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// chain builds the validation steps in the configured order
+func (c validationConfig) chain() ([]validator, error) {
+	var steps []validator
+	for _, name := range c.Steps {
+		switch name {
+		case "utf8":
+			steps = append(steps, validUTF8{})
+		case "strip":
+			steps = append(steps, stripInvisible{})
+		case "not_empty":
+			steps = append(steps, notEmpty{})
+		case "max_runes":
+			if c.MaxRunes <= 0 {
+				return nil, fmt.Errorf("max_runes must be positive, not %d", c.MaxRunes)
+			}
+			steps = append(steps, maxRunes(c.MaxRunes))
+		case "max_bytes":
+			if c.MaxBytes <= 0 {
+				return nil, fmt.Errorf("max_bytes must be positive, not %d", c.MaxBytes)
+			}
+			steps = append(steps, maxBytes(c.MaxBytes))
+		case "blocked_words":
+			steps = append(steps, newBlockedWords(c.BlockedWords))
+		default:
+			return nil, fmt.Errorf("unknown validation step %q", name)
+		}
+	}
+	return steps, nil
+}
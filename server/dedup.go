@@ -0,0 +1,70 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// dedupKey is one message of one sender, message IDs are chosen by the clients
+type dedupKey struct {
+	clientID  string
+	messageID string
+}
+
+// dedupWindow remembers the last size published messages, so a Publish that is retried
+// after a timeout is not posted twice. It counts messages instead of time, so every
+// node of a cluster forgets the same ones
+type dedupWindow struct {
+	size  int
+	seen  map[dedupKey]bool
+	order []dedupKey // oldest first
+}
+
+func newDedupWindow(size int) *dedupWindow {
+	return &dedupWindow{size: size, seen: make(map[dedupKey]bool)}
+}
+
+// contains tells whether the message was already published
+func (w *dedupWindow) contains(clientID, messageID string) bool {
+	return w.seen[dedupKey{clientID, messageID}]
+}
+
+// add remembers a published message and forgets the oldest one once the window is full.
+// It returns false if the message was already in the window
+func (w *dedupWindow) add(clientID, messageID string) bool {
+	key := dedupKey{clientID, messageID}
+	if w.seen[key] {
+		return false
+	}
+	if w.size <= 0 {
+		return true
+	}
+	w.seen[key] = true
+	w.order = append(w.order, key)
+	if len(w.order) > w.size {
+		delete(w.seen, w.order[0])
+		w.order = w.order[1:]
+	}
+	return true
+}
+
+// load fills the window from the history, e.g. after a restart
+func (w *dedupWindow) load(events []*proto.BroadCast) {
+	w.seen = make(map[dedupKey]bool)
+	w.order = nil
+	for _, event := range events {
+		if event.GetMessageId() != "" && (event.GetType() == proto.BroadCast_CHAT || event.GetType() == proto.BroadCast_DIRECT) {
+			w.add(event.GetClientId(), event.GetMessageId())
+		}
+	}
+}
+
+// newMessageID picks an ID for a message whose client did not send one
+func newMessageID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
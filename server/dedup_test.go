@@ -0,0 +1,62 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"testing"
+)
+
+func TestDedupWindow(t *testing.T) {
+	type add struct {
+		clientID, messageID string
+		fresh               bool // add returns true
+	}
+	tests := []struct {
+		name string
+		size int
+		adds []add
+	}{
+		{"new messages", 3, []add{{"alice", "a", true}, {"alice", "b", true}}},
+		{"retry", 3, []add{{"alice", "a", true}, {"alice", "a", false}}},
+		{"same ID of another sender", 3, []add{{"alice", "a", true}, {"bob", "a", true}}},
+		{"oldest is forgotten", 2, []add{{"alice", "a", true}, {"alice", "b", true}, {"alice", "c", true}, {"alice", "a", true}}},
+		{"retry does not move it up", 2, []add{{"alice", "a", true}, {"alice", "b", true}, {"alice", "a", false}, {"alice", "c", true}, {"alice", "a", true}}},
+		{"size 0 remembers nothing", 0, []add{{"alice", "a", true}, {"alice", "a", true}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newDedupWindow(test.size)
+			for i, a := range test.adds {
+				if got := w.add(a.clientID, a.messageID); got != a.fresh {
+					t.Fatalf("add %d (%s, %s) = %v, want %v", i, a.clientID, a.messageID, got, a.fresh)
+				}
+			}
+			if len(w.order) > test.size || len(w.seen) != len(w.order) {
+				t.Fatalf("window holds %d keys in order and %d in seen, size %d", len(w.order), len(w.seen), test.size)
+			}
+		})
+	}
+}
+
+func TestDedupWindowLoad(t *testing.T) {
+	w := newDedupWindow(2)
+	w.load([]*proto.BroadCast{
+		{Type: proto.BroadCast_CHAT, ClientId: "alice", MessageId: "a"},
+		{Type: proto.BroadCast_JOIN, ClientId: "bob"},
+		{Type: proto.BroadCast_EDIT, ClientId: "alice", MessageId: "a"},
+		{Type: proto.BroadCast_DIRECT, ClientId: "bob", MessageId: "b"},
+		{Type: proto.BroadCast_CHAT, ClientId: "carol", MessageId: "c"},
+	})
+	tests := []struct {
+		clientID, messageID string
+		want                bool
+	}{
+		{"alice", "a", false}, // pushed out by the two after it
+		{"bob", "b", true},
+		{"carol", "c", true},
+	}
+	for _, test := range tests {
+		if got := w.contains(test.clientID, test.messageID); got != test.want {
+			t.Errorf("contains(%s, %s) = %v, want %v", test.clientID, test.messageID, got, test.want)
+		}
+	}
+}
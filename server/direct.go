@@ -0,0 +1,51 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// publishDirect sends a private message to one participant in the room and echoes it
+// to the sender. It is stamped with the rooms clock like any other message.
+// Must be called with s.mutex held
+func (s *ChitChatServer) publishDirect(r *room, req *proto.PublishRequest) (*proto.PublishResponse, error) {
+	clientID := req.GetClientId()
+	recipient := req.GetRecipient()
+
+	// Every device of the recipient and of the sender gets it
+	targets := r.sessionsOf(recipient)
+	if len(targets) == 0 {
+		return nil, status.Errorf(codes.NotFound, "%s is not subscribed to room %s", recipient, r.name)
+	}
+	for session, sub := range r.sessionsOf(clientID) {
+		targets[session] = sub
+	}
+
+	currentTime := r.clock.Merge(req.GetTimestamp())
+	broadcast := &proto.BroadCast{
+		Type:      proto.BroadCast_DIRECT,
+		ClientId:  clientID,
+		Recipient: recipient,
+		Message:   req.GetText(),
+		Timestamp: currentTime,
+		MessageId: req.GetMessageId(),
+		ParentId:  req.GetParentId(),
+	}
+	s.record(r, broadcast)
+	s.broadcast(r, broadcast, targets)
+
+	log.Printf("Server Direct received: room=%s from=%s to=%s logical_time=%d message_id=%s", r.name, clientID, recipient, currentTime, req.GetMessageId())
+	return &proto.PublishResponse{Ack: true, MessageId: req.GetMessageId()}, nil
+}
+
+// visibleTo tells whether a persisted broadcast may be replayed to a participant,
+// private messages and changes to them only go back to the two people in them
+func visibleTo(broadcast *proto.BroadCast, clientID string) bool {
+	if broadcast.GetRecipient() == "" {
+		return true
+	}
+	return broadcast.GetClientId() == clientID || broadcast.GetRecipient() == clientID
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// EditMessage replaces the text of a message, for its author or an admin
+func (s *ChitChatServer) EditMessage(ctx context.Context, req *proto.EditRequest) (*proto.EditResponse, error) {
+	response := &proto.EditResponse{Ack: true}
+	err := s.update(func() (*proto.ChatCommand, error) {
+		r, err := s.room(req.GetRoom())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+			return nil, err
+		}
+		text, rejected := validate(s.validators, req.GetText())
+		if rejected != nil {
+			response = &proto.EditResponse{Ack: false, Error: rejected.reason, ErrorCode: rejected.code}
+			return nil, nil
+		}
+		target, err := s.changeable(r, req.GetMessageId(), req.GetClientId())
+		if err != nil {
+			return nil, err
+		}
+
+		return s.amend(r, target, &proto.BroadCast{
+			Type:      proto.BroadCast_EDIT,
+			ClientId:  req.GetClientId(),
+			Message:   text,
+			Timestamp: req.GetTimestamp(),
+			MessageId: req.GetMessageId(),
+		}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DeleteMessage takes a message back, for its author or an admin
+func (s *ChitChatServer) DeleteMessage(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	err := s.update(func() (*proto.ChatCommand, error) {
+		r, err := s.room(req.GetRoom())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+			return nil, err
+		}
+		target, err := s.changeable(r, req.GetMessageId(), req.GetClientId())
+		if err != nil {
+			return nil, err
+		}
+
+		return s.amend(r, target, &proto.BroadCast{
+			Type:      proto.BroadCast_DELETE,
+			ClientId:  req.GetClientId(),
+			Timestamp: req.GetTimestamp(),
+			MessageId: req.GetMessageId(),
+		}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.DeleteResponse{Ack: true}, nil
+}
+
+// changeable finds the message clientID wants to edit or delete and checks they may.
+// Must be called with s.mutex held
+func (s *ChitChatServer) changeable(r *room, messageID, clientID string) (*proto.BroadCast, error) {
+	target, deleted := s.message(r, messageID)
+	if target == nil {
+		return nil, status.Errorf(codes.NotFound, "no message %q in room %s", messageID, r.name)
+	}
+	if deleted {
+		return nil, status.Errorf(codes.FailedPrecondition, "message %q was deleted", messageID)
+	}
+	if target.GetClientId() != clientID && !s.admins[clientID] {
+		return nil, status.Errorf(codes.PermissionDenied, "only %s or an admin may change message %q", target.GetClientId(), messageID)
+	}
+	return target, nil
+}
+
+// amend stamps an EDIT, DELETE or REACTION with the rooms clock and sends it to everyone who got
+// the message, or returns the command that puts it in the cluster log first. Must be called with s.mutex held
+func (s *ChitChatServer) amend(r *room, target, change *proto.BroadCast) *proto.ChatCommand {
+	change.Recipient = target.GetRecipient()
+	if s.cluster != nil {
+		change.Room = r.name
+		return eventCommand(change)
+	}
+	change.Timestamp = r.clock.Merge(change.GetTimestamp())
+	s.deliverAmend(r, target, change)
+	return nil
+}
+
+// deliverAmend records a stamped EDIT, DELETE or REACTION. A change to a private message only goes
+// to the two people in it. Must be called with s.mutex held
+func (s *ChitChatServer) deliverAmend(r *room, target, change *proto.BroadCast) {
+	if target.GetType() == proto.BroadCast_DIRECT {
+		targets := r.sessionsOf(target.GetRecipient())
+		for session, sub := range r.sessionsOf(target.GetClientId()) {
+			targets[session] = sub
+		}
+		s.record(r, change)
+		s.broadcast(r, change, targets)
+	} else {
+		s.emit(r, change)
+	}
+	log.Printf("Server %s: room=%s message_id=%s by=%s logical_time=%d", change.GetType(), r.name, change.GetMessageId(), change.GetClientId(), change.GetTimestamp())
+}
+
+// fold applies the edits, deletes and reactions in a replay to the messages they change,
+// so a late joiner sees every message as it is now. Changes whose message is not part
+// of the replay are kept as they are
+func fold(events []*proto.BroadCast) []*proto.BroadCast {
+	messages := make(map[string]int) // message ID -> index in folded
+	var folded []*proto.BroadCast
+	for _, event := range events {
+		switch event.GetType() {
+		case proto.BroadCast_CHAT, proto.BroadCast_DIRECT:
+			if event.GetMessageId() != "" {
+				messages[event.GetMessageId()] = len(folded)
+			}
+		case proto.BroadCast_EDIT, proto.BroadCast_DELETE, proto.BroadCast_REACTION:
+			i, ok := messages[event.GetMessageId()]
+			if !ok {
+				break
+			}
+			//Copy, the history itself stays as it happened
+			message := protobuf.Clone(folded[i]).(*proto.BroadCast)
+			switch event.GetType() {
+			case proto.BroadCast_EDIT:
+				message.Message = event.GetMessage()
+				message.Edited = true
+			case proto.BroadCast_DELETE:
+				message.Message = ""
+				message.Deleted = true
+			case proto.BroadCast_REACTION:
+				message.Reactions = event.GetReactions()
+			}
+			folded[i] = message
+			continue
+		}
+		folded = append(folded, event)
+	}
+	return folded
+}
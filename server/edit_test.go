@@ -0,0 +1,62 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestChangeable(t *testing.T) {
+	s := newTestServer(t)
+	s.admins["root"] = true
+	r := s.rooms[defaultRoom]
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "hi", MessageId: "m1"})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "oops", MessageId: "m2"})
+	s.deliverAmend(r, r.messages["m2"].message, &proto.BroadCast{Type: proto.BroadCast_DELETE, ClientId: "alice", MessageId: "m2"})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_DIRECT, ClientId: "bob", Recipient: "alice", Message: "psst", MessageId: "m3"})
+
+	tests := []struct {
+		name      string
+		messageID string
+		clientID  string
+		code      codes.Code
+	}{
+		{"author", "m1", "alice", codes.OK},
+		{"admin", "m1", "root", codes.OK},
+		{"someone else", "m1", "bob", codes.PermissionDenied},
+		{"deleted", "m2", "alice", codes.FailedPrecondition},
+		{"unknown", "m9", "alice", codes.NotFound},
+		{"author of a private message", "m3", "bob", codes.OK},
+		{"recipient of a private message", "m3", "alice", codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target, err := s.changeable(r, test.messageID, test.clientID)
+			if status.Code(err) != test.code {
+				t.Fatalf("changeable = %v, want %v", err, test.code)
+			}
+			if err == nil && target.GetMessageId() != test.messageID {
+				t.Fatalf("found message %q, want %q", target.GetMessageId(), test.messageID)
+			}
+		})
+	}
+}
+
+func TestMessageIndexSurvivesARestart(t *testing.T) {
+	s := newTestServer(t)
+	r := s.rooms[defaultRoom]
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "hi", MessageId: "m1"})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "oops", MessageId: "m2"})
+	s.deliverAmend(r, r.messages["m2"].message, &proto.BroadCast{Type: proto.BroadCast_DELETE, ClientId: "alice", MessageId: "m2"})
+
+	restarted := newChitChatServer(s.history, 16, dropOldest, false, 100)
+	r = restarted.rooms[defaultRoom]
+	if target, deleted := restarted.message(r, "m1"); target == nil || deleted {
+		t.Fatalf("m1 = %v deleted=%v after the restart", target, deleted)
+	}
+	if target, deleted := restarted.message(r, "m2"); target == nil || !deleted {
+		t.Fatalf("m2 = %v deleted=%v after the restart, want it deleted", target, deleted)
+	}
+}
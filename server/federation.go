@@ -0,0 +1,302 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	peerQueueSize = 1024 // events a slow peer may lag behind before it has to catch up again
+	federateRetry = 2 * time.Second
+)
+
+// peerLink is a Federate stream to another server, with its own bounded queue
+type peerLink struct {
+	server string
+	queue  chan *proto.BroadCast
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (p *peerLink) close() {
+	p.once.Do(func() { close(p.closed) })
+}
+
+// federationStream is what both ends of a Federate stream have in common
+type federationStream interface {
+	Send(*proto.FederationMessage) error
+	Recv() (*proto.FederationMessage, error)
+	Context() context.Context
+}
+
+// federator serves the Federation service of a ChitChatServer
+type federator struct {
+	proto.UnimplementedFederationServer
+	chat *ChitChatServer
+}
+
+// Federate answers a peer that dialed us
+func (f *federator) Federate(stream proto.Federation_FederateServer) error {
+	return f.chat.exchange(stream, "")
+}
+
+// federates tells whether an event type is shared with other servers. Private
+// messages, acks, heartbeats and shutdowns stay on the server they happened on
+func federates(broadcast *proto.BroadCast) bool {
+	switch broadcast.GetType() {
+	case proto.BroadCast_CHAT, proto.BroadCast_JOIN, proto.BroadCast_LEAVE, proto.BroadCast_EDIT, proto.BroadCast_DELETE, proto.BroadCast_REACTION, proto.BroadCast_PRESENCE:
+		return broadcast.GetRecipient() == ""
+	}
+	return false
+}
+
+// federatedID is the user@server name a participant has on other servers
+func federatedID(clientID, server string) string {
+	if strings.Contains(clientID, "@") {
+		return clientID
+	}
+	return clientID + "@" + server
+}
+
+// federateWith keeps a link to the peer at addr open, reconnecting when it breaks
+func (s *ChitChatServer) federateWith(addr string, creds credentials.TransportCredentials) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("Server STARTUP_ERROR: bad federation peer address %s: %v", addr, err)
+	}
+	defer conn.Close()
+	client := proto.NewFederationClient(conn)
+
+	for {
+		stream, err := client.Federate(context.Background())
+		if err == nil {
+			err = s.exchange(stream, addr)
+		}
+		if code := status.Code(err); err != nil && code != codes.Unavailable {
+			log.Printf("Server FEDERATION: %s: %v", addr, status.Convert(err).Message())
+		}
+		s.mutex.Lock()
+		closing := s.closing
+		s.mutex.Unlock()
+		if closing {
+			return
+		}
+		time.Sleep(federateRetry)
+	}
+}
+
+// exchange runs one Federate stream from either end: hello both ways, then each side
+// sends what the other is missing followed by every new event. addr is empty on the
+// answering end
+func (s *ChitChatServer) exchange(stream federationStream, addr string) error {
+	s.mutex.Lock()
+	if s.name == "" {
+		s.mutex.Unlock()
+		return status.Error(codes.FailedPrecondition, "federation is not enabled on this server")
+	}
+	hello := &proto.FederationHello{Server: s.name, Seen: make(map[string]int64)}
+	for origin, id := range s.seen {
+		hello.Seen[origin] = id
+	}
+	s.mutex.Unlock()
+
+	if err := stream.Send(&proto.FederationMessage{Message: &proto.FederationMessage_Hello{Hello: hello}}); err != nil {
+		return err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	theirs := first.GetHello()
+	if theirs == nil || theirs.GetServer() == "" {
+		return status.Error(codes.InvalidArgument, "a Federate stream starts with a hello")
+	}
+	if theirs.GetServer() == hello.Server {
+		return status.Errorf(codes.FailedPrecondition, "peer %s has our own name %s", addr, hello.Server)
+	}
+	if err := s.authenticateFederation(stream.Context(), theirs.GetServer()); err != nil {
+		log.Printf("Server PEER_REJECTED: Federate: %v", status.Convert(err).Message())
+		return err
+	}
+
+	//Take the catch-up and register for new events in one go, so nothing falls in between
+	s.mutex.Lock()
+	if s.closing {
+		s.mutex.Unlock()
+		return status.Error(codes.Unavailable, "server is shutting down")
+	}
+	var catchUp []*proto.BroadCast
+	for _, event := range s.history.events {
+		if federates(event) && event.GetOrigin() == s.name && event.GetEventId() > theirs.GetSeen()[s.name] {
+			catchUp = append(catchUp, event)
+		}
+	}
+	link := &peerLink{
+		server: theirs.GetServer(),
+		queue:  make(chan *proto.BroadCast, peerQueueSize),
+		closed: make(chan struct{}),
+	}
+	s.peers[link] = true
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.peers, link)
+		s.mutex.Unlock()
+		link.close()
+		log.Printf("Server FEDERATION: link to %s closed", link.server)
+	}()
+	log.Printf("Server FEDERATION: linked with %s, sending %d events it missed", link.server, len(catchUp))
+
+	received := make(chan error, 1)
+	go func() {
+		for {
+			message, err := stream.Recv()
+			if err != nil {
+				received <- err
+				return
+			}
+			if event := message.GetEvent(); event != nil {
+				s.relayIn(link.server, event)
+			}
+		}
+	}()
+
+	for _, event := range catchUp {
+		if err := s.sendPeer(stream, event); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case err := <-received:
+			return err
+		case <-link.closed:
+			return status.Error(codes.Aborted, "federation link dropped, connect again")
+		case event := <-link.queue:
+			if err := s.sendPeer(stream, event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendPeer sends an event with the participant named user@server, the vector clock
+// only makes sense on the server that kept it
+func (s *ChitChatServer) sendPeer(stream federationStream, event *proto.BroadCast) error {
+	out := protobuf.Clone(event).(*proto.BroadCast)
+	out.ClientId = federatedID(out.ClientId, out.Origin)
+	out.Vector = nil
+	return stream.Send(&proto.FederationMessage{Message: &proto.FederationMessage_Event{Event: out}})
+}
+
+// federate queues an event of ours for every linked peer. Events from peers are not passed
+// on, every server links with every other. A peer whose queue is full is dropped, it
+// catches up when it links again. Must be called with s.mutex held
+func (s *ChitChatServer) federate(event *proto.BroadCast) {
+	if event.GetOrigin() != s.name {
+		return
+	}
+	for link := range s.peers {
+		select {
+		case link.queue <- event:
+		default:
+			log.Printf("Server FEDERATION: %s fell %d events behind, dropping the link", link.server, peerQueueSize)
+			delete(s.peers, link)
+			link.close()
+		}
+	}
+}
+
+// relayIn takes an event from a peer into the room of the same name, once per event ID.
+// A peer only sends its own events, by its own participants. Our clock merges theirs, so
+// the event sorts after everything it saw
+func (s *ChitChatServer) relayIn(peer string, event *proto.BroadCast) {
+	if !federates(event) || event.GetEventId() <= 0 {
+		log.Printf("Server FEDERATION: ignoring a malformed event from %s", peer)
+		return
+	}
+	origin := event.GetOrigin()
+	if origin != peer || !strings.HasSuffix(event.GetClientId(), "@"+peer) {
+		log.Printf("Server FEDERATION: ignoring an event of %s by %s from %s", origin, event.GetClientId(), peer)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if event.GetEventId() <= s.seen[origin] {
+		return // sent again when the link came back
+	}
+	s.seen[origin] = event.GetEventId()
+
+	name := roomOf(event)
+	r, ok := s.rooms[name]
+	if !ok {
+		r = newRoom(name, 0, s.vectorMode)
+		s.rooms[name] = r
+		log.Printf("Server ROOM_CREATED: %s (from %s)", name, peer)
+	}
+
+	clientID := event.GetClientId()
+	switch event.GetType() {
+	case proto.BroadCast_CHAT:
+		if target, _ := s.message(r, event.GetMessageId()); target != nil {
+			log.Printf("Server FEDERATION: ignoring a message of %s, its ID %s is taken", clientID, event.GetMessageId())
+			return
+		}
+		if parentID := event.GetParentId(); parentID != "" {
+			if parent, _ := s.message(r, parentID); parent == nil || !visibleTo(parent, clientID) {
+				log.Printf("Server FEDERATION: ignoring a reply of %s to %s, we do not have that message", clientID, parentID)
+				return
+			}
+		}
+	case proto.BroadCast_EDIT, proto.BroadCast_DELETE, proto.BroadCast_REACTION:
+		//A peer only speaks for its own participants, and they may only change their own messages
+		target, deleted := s.message(r, event.GetMessageId())
+		if target == nil || deleted || !visibleTo(target, clientID) {
+			log.Printf("Server FEDERATION: ignoring a %s of %s, we do not have message %s", event.GetType(), clientID, event.GetMessageId())
+			return
+		}
+		if event.GetType() != proto.BroadCast_REACTION && target.GetClientId() != clientID {
+			log.Printf("Server FEDERATION: ignoring a %s of %s, message %s is by %s", event.GetType(), clientID, event.GetMessageId(), target.GetClientId())
+			return
+		}
+	case proto.BroadCast_JOIN:
+		r.present[clientID] = map[string]bool{origin: true}
+	case proto.BroadCast_LEAVE:
+		delete(r.present, clientID)
+	}
+	relayed := &proto.BroadCast{
+		Type:      event.GetType(),
+		ClientId:  clientID,
+		Message:   event.GetMessage(),
+		Origin:    origin,
+		EventId:   event.GetEventId(),
+		MessageId: event.GetMessageId(),
+		ParentId:  event.GetParentId(),
+		Reaction:  event.GetReaction(),
+		Removed:   event.GetRemoved(),
+		Status:    event.GetStatus(),
+	}
+	//Our counts also hold reactions the origin has not seen yet
+	if relayed.Type == proto.BroadCast_REACTION && !s.tally(r, relayed) {
+		return
+	}
+	remoteTime := event.GetTimestamp()
+	relayed.Timestamp = r.clock.Merge(remoteTime)
+	s.emit(r, relayed)
+	log.Printf("Server FEDERATION: room=%s type=%s from=%s via %s remote_time=%d logical_time=%d",
+		r.name, event.GetType(), clientID, peer, remoteTime, r.clock.Now())
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"testing"
+)
+
+func TestRelayInOnlyTakesEventsOfThePeer(t *testing.T) {
+	chat := func(clientID, origin string, eventID int64) *proto.BroadCast {
+		return &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: clientID, Message: "hi", Room: defaultRoom, Origin: origin, EventId: eventID}
+	}
+
+	tests := []struct {
+		name   string
+		events []*proto.BroadCast
+		kept   int
+	}{
+		{"own event of the peer", []*proto.BroadCast{chat("bob@bergen", "bergen", 1)}, 1},
+		{"sent twice", []*proto.BroadCast{chat("bob@bergen", "bergen", 1), chat("bob@bergen", "bergen", 1)}, 1},
+		{"older than what we have", []*proto.BroadCast{chat("bob@bergen", "bergen", 5), chat("bob@bergen", "bergen", 4)}, 1},
+		{"another origin", []*proto.BroadCast{chat("eve@trondheim", "trondheim", 1)}, 0},
+		{"our own name", []*proto.BroadCast{chat("alice@oslo", "oslo", 1)}, 0},
+		{"participant of another server", []*proto.BroadCast{chat("alice@oslo", "bergen", 1)}, 0},
+		{"participant without server", []*proto.BroadCast{chat("bob", "bergen", 1)}, 0},
+		{"no event ID", []*proto.BroadCast{chat("bob@bergen", "bergen", 0)}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.name = "oslo"
+			for _, event := range test.events {
+				s.relayIn("bergen", event)
+			}
+			if got := len(s.history.events); got != test.kept {
+				t.Fatalf("history has %d events, want %d", got, test.kept)
+			}
+		})
+	}
+}
+
+func TestEventIDsGrowAcrossRestarts(t *testing.T) {
+	first := newTestServer(t)
+	first.name, first.epoch = "oslo", 1000
+	first.emit(first.rooms[defaultRoom], &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice"})
+	before := first.history.events[0].EventId
+
+	// Without a history the next run only has its start time to go on
+	second := newTestServer(t)
+	second.name, second.epoch = "oslo", 2000
+	second.emit(second.rooms[defaultRoom], &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice"})
+	second.emit(second.rooms[defaultRoom], &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice"})
+	if after := second.history.events[0].EventId; after <= before {
+		t.Fatalf("event ID %d after the restart is not above %d", after, before)
+	}
+	if a, b := second.history.events[0].EventId, second.history.events[1].EventId; b != a+1 {
+		t.Fatalf("event IDs %d and %d within a run do not follow each other", a, b)
+	}
+}
+
+func TestRelayInOnlyChangesMessagesOfTheAuthor(t *testing.T) {
+	event := func(kind proto.BroadCast_Type, messageID string) *proto.BroadCast {
+		return &proto.BroadCast{Type: kind, ClientId: "bob@bergen", Message: "changed", Room: defaultRoom, Origin: "bergen", EventId: 10, MessageId: messageID, Reaction: "👍"}
+	}
+	reply := func(parentID string) *proto.BroadCast {
+		return &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "bob@bergen", Message: "re", Room: defaultRoom, Origin: "bergen", EventId: 10, MessageId: "b9", ParentId: parentID}
+	}
+
+	tests := []struct {
+		name  string
+		event *proto.BroadCast
+		kept  bool
+	}{
+		{"edit of a local users message", event(proto.BroadCast_EDIT, "a1"), false},
+		{"delete of a local users message", event(proto.BroadCast_DELETE, "a1"), false},
+		{"edit of a third servers message", event(proto.BroadCast_EDIT, "e1"), false},
+		{"edit of its own message", event(proto.BroadCast_EDIT, "b1"), true},
+		{"delete of its own message", event(proto.BroadCast_DELETE, "b1"), true},
+		{"edit of an unknown message", event(proto.BroadCast_EDIT, "x1"), false},
+		{"reaction to a local users message", event(proto.BroadCast_REACTION, "a1"), true},
+		{"reaction to an unknown message", event(proto.BroadCast_REACTION, "x1"), false},
+		{"reply to a local users message", reply("a1"), true},
+		{"reply to an unknown message", reply("x1"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.name = "oslo"
+			r := s.rooms[defaultRoom]
+			s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "hi", MessageId: "a1"})
+			s.relayIn("bergen", &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "bob@bergen", Message: "hi", Room: defaultRoom, Origin: "bergen", EventId: 1, MessageId: "b1"})
+			s.relayIn("trondheim", &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "eve@trondheim", Message: "hi", Room: defaultRoom, Origin: "trondheim", EventId: 1, MessageId: "e1"})
+			before := len(s.history.events)
+
+			s.relayIn("bergen", test.event)
+			if kept := len(s.history.events) > before; kept != test.kept {
+				t.Fatalf("kept = %v, want %v", kept, test.kept)
+			}
+			if target, _ := s.message(r, "a1"); target.GetMessage() != "hi" {
+				t.Fatalf("alice's message reads %q", target.GetMessage())
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"log"
+	"time"
+)
+
+// heartbeat queues a HEARTBEAT for every subscriber each interval, so clients notice
+// when the server went quiet. A session that got nothing through for misses intervals
+// is treated as dead (half-open connection, stuck client) and evicted with a LEAVE.
+// Heartbeats are not persisted and do not advance the clocks
+func (s *ChitChatServer) heartbeat(interval time.Duration, misses int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.mutex.Lock()
+		if s.closing {
+			s.mutex.Unlock()
+			return
+		}
+		for _, r := range s.rooms {
+			for _, sub := range r.subscribers {
+				if sub.idle(now) > time.Duration(misses)*interval {
+					log.Printf("Session %s missed %d heartbeats in room %s", sub.session, misses, r.name)
+					s.evict(r, sub, "timeout")
+				}
+			}
+			s.broadcast(r, &proto.BroadCast{
+				Type:      proto.BroadCast_HEARTBEAT,
+				Timestamp: r.clock.Now(),
+				Room:      r.name,
+			}, r.subscribers)
+		}
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// history is an append-only log of every broadcast.
+// On disk each record is a uvarint length followed by the serialized BroadCast,
+// so the log can be replayed after a restart. An empty path keeps it in memory only
+type history struct {
+	file   *os.File
+	events []*proto.BroadCast
+}
+
+// openHistory loads all records from path and opens it for appending.
+// A half written record at the end (crash during append) is cut off
+func openHistory(path string) (*history, error) {
+	h := &history{}
+	if path == "" {
+		return h, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	var good int64 // offset just after the last complete record
+	for {
+		size, err := binary.ReadUvarint(reader)
+		if err != nil {
+			break
+		}
+		record := make([]byte, size)
+		if _, err := io.ReadFull(reader, record); err != nil {
+			break
+		}
+		broadcast := &proto.BroadCast{}
+		if err := protobuf.Unmarshal(record, broadcast); err != nil {
+			break
+		}
+		h.events = append(h.events, broadcast)
+		good += int64(uvarintLen(size)) + int64(size)
+	}
+
+	if err := file.Truncate(good); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("truncate history: %w", err)
+	}
+	if _, err := file.Seek(good, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	h.file = file
+
+	log.Printf("Server HISTORY: loaded %d broadcasts from %s", len(h.events), path)
+	return h, nil
+}
+
+// append writes the broadcast to disk before it is kept in memory
+func (h *history) append(broadcast *proto.BroadCast) error {
+	if h.file != nil {
+		record, err := protobuf.Marshal(broadcast)
+		if err != nil {
+			return err
+		}
+		buf := binary.AppendUvarint(nil, uint64(len(record)))
+		buf = append(buf, record...)
+		if _, err := h.file.Write(buf); err != nil {
+			return err
+		}
+		if err := h.file.Sync(); err != nil {
+			return err
+		}
+	}
+	h.events = append(h.events, broadcast)
+	return nil
+}
+
+// replay returns the last N broadcasts of a room the client may see if lastN is set,
+// otherwise every one of them with a timestamp after since. Edits and deletes are folded
+// into the messages they change
+func (h *history) replay(room, clientID string, since int64, lastN int) []*proto.BroadCast {
+	var events []*proto.BroadCast
+	for _, event := range h.events {
+		if roomOf(event) == room && event.GetType() != proto.BroadCast_ROOM_CREATED && visibleTo(event, clientID) &&
+			(lastN > 0 || event.GetTimestamp() > since) {
+			events = append(events, event)
+		}
+	}
+	events = fold(events)
+	//Folded changes are not replayed, point past them so clients do not wait for them
+	var last int64
+	for i, event := range events {
+		if i > 0 && event.GetPrevious() > last {
+			event = protobuf.Clone(event).(*proto.BroadCast)
+			event.Previous = last
+			events[i] = event
+		}
+		if event.GetRecipient() == "" {
+			last = event.GetTimestamp()
+		}
+	}
+	if lastN > 0 && len(events) > lastN {
+		events = events[len(events)-lastN:]
+	}
+	return events
+}
+
+func (h *history) close() error {
+	if h.file == nil {
+		return nil
+	}
+	return h.file.Close()
+}
+
+func uvarintLen(x uint64) int {
+	return len(binary.AppendUvarint(nil, x))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// posted is a CHAT or DIRECT of a room and what happened to it since, so finding a
+// message by its ID, reading its thread or counting its reactions does not go through
+// the whole history
+type posted struct {
+	message *proto.BroadCast // as it was sent
+	current *proto.BroadCast // as it reads now, with edits, delete and reactions folded in
+	deleted bool
+	replies []string                   // IDs of the replies to it, in the order they came in
+	given   map[string]map[string]bool // reaction -> who gave it
+}
+
+// index keeps the messages of a room up to date with a recorded broadcast
+func (r *room) index(broadcast *proto.BroadCast) {
+	id := broadcast.GetMessageId()
+	if id == "" {
+		return
+	}
+	switch broadcast.GetType() {
+	case proto.BroadCast_CHAT, proto.BroadCast_DIRECT:
+		r.messages[id] = &posted{message: broadcast, current: broadcast}
+		if parent, ok := r.messages[broadcast.GetParentId()]; ok {
+			parent.replies = append(parent.replies, id)
+		}
+		return
+	}
+
+	p, ok := r.messages[id]
+	if !ok {
+		return
+	}
+	//Copy, what was handed out before stays as it was
+	current := protobuf.Clone(p.current).(*proto.BroadCast)
+	switch broadcast.GetType() {
+	case proto.BroadCast_EDIT:
+		current.Message = broadcast.GetMessage()
+		current.Edited = true
+	case proto.BroadCast_DELETE:
+		current.Message = ""
+		current.Deleted = true
+		p.deleted = true
+	case proto.BroadCast_REACTION:
+		current.Reactions = broadcast.GetReactions()
+		if p.given == nil {
+			p.given = make(map[string]map[string]bool)
+		}
+		applyReaction(p.given, broadcast)
+	default:
+		return
+	}
+	p.current = current
+}
+
+// message finds the CHAT or DIRECT with this ID in the room, and whether it was deleted
+// since. Must be called with s.mutex held
+func (s *ChitChatServer) message(r *room, messageID string) (target *proto.BroadCast, deleted bool) {
+	p, ok := r.messages[messageID]
+	if !ok {
+		return nil, false
+	}
+	return p.message, p.deleted
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// The Replication, Raft and Federation services are for other servers only. They share the
+// port with the clients, so every call to them must come from a server we know: under mutual
+// TLS the CN of its certificate has to be one of -peer-ids. Plaintext setups have no way to
+// tell servers from clients and have to allow everyone explicitly with -insecure-peers
+
+// authenticatePeer returns who is calling an internal service. It is the CN of the callers
+// certificate, or empty when -insecure-peers lets in a caller without one
+func (s *ChitChatServer) authenticatePeer(ctx context.Context) (string, error) {
+	cn, ok := peerID(ctx)
+	if ok && s.allowedPeer(cn) {
+		return cn, nil
+	}
+	if s.insecurePeers {
+		return "", nil
+	}
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "internal services need a server certificate (mutual TLS)")
+	}
+	return "", status.Errorf(codes.PermissionDenied, "%s is not a server this one works with", cn)
+}
+
+// allowedPeer tells whether a certificate CN belongs to one of our peers. The nodes of
+// our cluster are let in by their node ID
+func (s *ChitChatServer) allowedPeer(cn string) bool {
+	if s.peerIDs[cn] {
+		return true
+	}
+	if s.cluster != nil {
+		for _, member := range s.cluster.Members() {
+			if member.Id == cn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// raftCaller is the node ID of the cluster node making a Raft call, the CN of its
+// certificate. Empty under -insecure-peers without one, then nobody can be checked
+func raftCaller(ctx context.Context) string {
+	cn, _ := peerID(ctx)
+	return cn
+}
+
+// authorizeProposal only lets a node commit commands for its own runs. The node checked
+// the session token of the participant, the leader cannot
+func authorizeProposal(caller string, req *proto.ProposeRequest) error {
+	command := &proto.ChatCommand{}
+	if err := protobuf.Unmarshal(req.GetCommand(), command); err != nil {
+		return fmt.Errorf("command does not decode: %v", err)
+	}
+	if node, _, _ := strings.Cut(command.GetNode(), "@"); node != caller {
+		return fmt.Errorf("%s may not propose commands for node %s", caller, node)
+	}
+	return nil
+}
+
+// authenticateFederation checks the name a federation peer gives in its hello, on either
+// end of the link. Under mutual TLS it has to be the CN of its certificate, so no server
+// can send events in the name of another
+func (s *ChitChatServer) authenticateFederation(ctx context.Context, name string) error {
+	if s.insecurePeers {
+		return nil
+	}
+	cn, ok := peerID(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "federation needs mutual TLS or -insecure-peers")
+	}
+	if cn != name || !s.allowedPeer(cn) {
+		return status.Errorf(codes.PermissionDenied, "certificate %s may not federate as %s", cn, name)
+	}
+	return nil
+}
+
+// parsePeerIDs reads the comma separated -peer-ids
+func parsePeerIDs(list string) map[string]bool {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
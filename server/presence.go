@@ -0,0 +1,125 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// participant is what the server saw of someone in a room since their JOIN
+type participant struct {
+	joinedAt   int64     // logical time of the JOIN
+	joinedWall time.Time // when the JOIN got here
+	lastActive time.Time // last message or typing
+	away       bool
+}
+
+// track keeps the participants of a room up to date with a recorded broadcast
+func (r *room) track(broadcast *proto.BroadCast) {
+	clientID := broadcast.GetClientId()
+	switch broadcast.GetType() {
+	case proto.BroadCast_JOIN:
+		now := time.Now()
+		r.participants[clientID] = &participant{joinedAt: broadcast.GetTimestamp(), joinedWall: now, lastActive: now}
+	case proto.BroadCast_LEAVE:
+		delete(r.participants, clientID)
+	case proto.BroadCast_CHAT, proto.BroadCast_DIRECT:
+		r.active(clientID)
+	case proto.BroadCast_PRESENCE:
+		if p, ok := r.participants[clientID]; ok {
+			p.away = broadcast.GetStatus() == proto.ParticipantStatus_AWAY
+			p.lastActive = time.Now()
+		}
+	}
+}
+
+// active notes that a participant just did something
+func (r *room) active(clientID string) {
+	if p, ok := r.participants[clientID]; ok {
+		p.lastActive = time.Now()
+	}
+}
+
+// statusOf is AWAY if the participant said so, IDLE if they did nothing for idleAfter
+func (p *participant) statusOf(now time.Time, idleAfter time.Duration) proto.ParticipantStatus {
+	switch {
+	case p.away:
+		return proto.ParticipantStatus_AWAY
+	case idleAfter > 0 && now.Sub(p.lastActive) > idleAfter:
+		return proto.ParticipantStatus_IDLE
+	}
+	return proto.ParticipantStatus_ONLINE
+}
+
+// ListParticipants returns everyone in a room with their JOIN and status
+func (s *ChitChatServer) ListParticipants(ctx context.Context, req *proto.ListParticipantsRequest) (*proto.ListParticipantsResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	r, err := s.room(req.GetRoom())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	response := &proto.ListParticipantsResponse{}
+	for _, id := range r.members() {
+		info := &proto.Participant{ClientId: id}
+		//Members a restored cluster snapshot brought along have no JOIN here
+		if p, ok := r.participants[id]; ok {
+			info.JoinedAt = p.joinedAt
+			info.JoinedAtMs = p.joinedWall.UnixMilli()
+			info.Status = p.statusOf(now, s.idleAfter)
+		}
+		response.Participants = append(response.Participants, info)
+	}
+	sort.Slice(response.Participants, func(i, j int) bool {
+		return response.Participants[i].ClientId < response.Participants[j].ClientId
+	})
+	return response, nil
+}
+
+// SetStatus lets a participant say they are away or back, the room gets a PRESENCE
+func (s *ChitChatServer) SetStatus(ctx context.Context, req *proto.SetStatusRequest) (*proto.SetStatusResponse, error) {
+	err := s.update(func() (*proto.ChatCommand, error) {
+		r, err := s.room(req.GetRoom())
+		if err != nil {
+			return nil, err
+		}
+		clientID := req.GetClientId()
+		if _, err := authorize(ctx, r, clientID); err != nil {
+			return nil, err
+		}
+		if req.GetStatus() != proto.ParticipantStatus_ONLINE && req.GetStatus() != proto.ParticipantStatus_AWAY {
+			return nil, status.Errorf(codes.InvalidArgument, "status can be set to ONLINE or AWAY, not %s", req.GetStatus())
+		}
+		//Saying the same again changes nothing, but counts as activity
+		if p, ok := r.participants[clientID]; ok && p.away == (req.GetStatus() == proto.ParticipantStatus_AWAY) {
+			r.active(clientID)
+			return nil, nil
+		}
+
+		presence := &proto.BroadCast{
+			Type:      proto.BroadCast_PRESENCE,
+			ClientId:  clientID,
+			Timestamp: req.GetTimestamp(),
+			Status:    req.GetStatus(),
+		}
+		if s.cluster != nil {
+			presence.Room = r.name
+			return eventCommand(presence), nil
+		}
+		presence.Timestamp = r.clock.Merge(req.GetTimestamp())
+		s.emit(r, presence)
+		log.Printf("Server PRESENCE: room=%s %s is %s logical_time=%d", r.name, clientID, presence.Status, presence.Timestamp)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.SetStatusResponse{Ack: true}, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxReaction is how long a reaction may be, enough for any emoji sequence
+const maxReaction = 32
+
+// AddReaction adds the reaction of a participant to a message
+func (s *ChitChatServer) AddReaction(ctx context.Context, req *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return s.react(ctx, req, false)
+}
+
+// RemoveReaction takes the reaction of a participant back
+func (s *ChitChatServer) RemoveReaction(ctx context.Context, req *proto.ReactionRequest) (*proto.ReactionResponse, error) {
+	return s.react(ctx, req, true)
+}
+
+// react checks a reaction and broadcasts it, unless it changes nothing
+func (s *ChitChatServer) react(ctx context.Context, req *proto.ReactionRequest, removed bool) (*proto.ReactionResponse, error) {
+	change := &proto.BroadCast{
+		Type:      proto.BroadCast_REACTION,
+		ClientId:  req.GetClientId(),
+		Timestamp: req.GetTimestamp(),
+		MessageId: req.GetMessageId(),
+		Reaction:  req.GetReaction(),
+		Removed:   removed,
+	}
+	err := s.update(func() (*proto.ChatCommand, error) {
+		r, err := s.room(req.GetRoom())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+			return nil, err
+		}
+		if req.GetReaction() == "" || len(req.GetReaction()) > maxReaction {
+			return nil, status.Errorf(codes.InvalidArgument, "a reaction has 1 to %d bytes", maxReaction)
+		}
+		messageID := req.GetMessageId()
+		target, deleted := s.message(r, messageID)
+		if target == nil || !visibleTo(target, req.GetClientId()) {
+			return nil, status.Errorf(codes.NotFound, "no message %q in room %s", messageID, r.name)
+		}
+		if deleted {
+			return nil, status.Errorf(codes.FailedPrecondition, "message %q was deleted", messageID)
+		}
+
+		if !s.tally(r, change) {
+			return nil, nil
+		}
+		return s.amend(r, target, change), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ReactionResponse{Ack: true, Reactions: change.Reactions}, nil
+}
+
+// tally puts the counts of reactions on the message of a REACTION into it, as they are
+// once it is recorded. It returns false if the reaction was already given or taken back,
+// or the message is unknown, then the change is not broadcast. Must be called with s.mutex held
+func (s *ChitChatServer) tally(r *room, change *proto.BroadCast) bool {
+	p, ok := r.messages[change.GetMessageId()]
+	if !ok {
+		return false
+	}
+	counts := make(map[string]int32)
+	for reaction, clients := range p.given {
+		counts[reaction] = int32(len(clients))
+	}
+	already := p.given[change.GetReaction()][change.GetClientId()]
+	changed := already == change.GetRemoved()
+	if changed && change.GetRemoved() {
+		counts[change.GetReaction()]--
+	} else if changed {
+		counts[change.GetReaction()]++
+	}
+
+	change.Reactions = make(map[string]int32)
+	for reaction, count := range counts {
+		if count > 0 {
+			change.Reactions[reaction] = count
+		}
+	}
+	return changed
+}
+
+func applyReaction(given map[string]map[string]bool, event *proto.BroadCast) {
+	clients, ok := given[event.GetReaction()]
+	if !ok {
+		clients = make(map[string]bool)
+		given[event.GetReaction()] = clients
+	}
+	if event.GetRemoved() {
+		delete(clients, event.GetClientId())
+	} else {
+		clients[event.GetClientId()] = true
+	}
+}
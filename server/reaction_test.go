@@ -0,0 +1,72 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReactions(t *testing.T) {
+	s := newTestServer(t)
+	r := s.rooms[defaultRoom]
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "hi", MessageId: "m1"})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "oops", MessageId: "m2"})
+	s.deliverAmend(r, r.messages["m2"].message, &proto.BroadCast{Type: proto.BroadCast_DELETE, ClientId: "alice", MessageId: "m2"})
+
+	// The steps run in order on the same message
+	steps := []struct {
+		name      string
+		clientID  string
+		messageID string
+		reaction  string
+		removed   bool
+		code      codes.Code
+		counts    map[string]int32
+		recorded  bool
+	}{
+		{"first", "bob", "m1", "👍", false, codes.OK, map[string]int32{"👍": 1}, true},
+		{"same again", "bob", "m1", "👍", false, codes.OK, map[string]int32{"👍": 1}, false},
+		{"someone else", "carol", "m1", "👍", false, codes.OK, map[string]int32{"👍": 2}, true},
+		{"another reaction", "bob", "m1", "🎉", false, codes.OK, map[string]int32{"👍": 2, "🎉": 1}, true},
+		{"taken back", "bob", "m1", "👍", true, codes.OK, map[string]int32{"👍": 1, "🎉": 1}, true},
+		{"taken back again", "bob", "m1", "👍", true, codes.OK, map[string]int32{"👍": 1, "🎉": 1}, false},
+		{"never given", "dave", "m1", "🎉", true, codes.OK, map[string]int32{"👍": 1, "🎉": 1}, false},
+		{"deleted message", "bob", "m2", "👍", false, codes.FailedPrecondition, nil, false},
+		{"unknown message", "bob", "m9", "👍", false, codes.NotFound, nil, false},
+		{"empty", "bob", "m1", "", false, codes.InvalidArgument, nil, false},
+	}
+	for _, step := range steps {
+		before := len(s.history.events)
+		req := &proto.ReactionRequest{ClientId: step.clientID, Room: defaultRoom, MessageId: step.messageID, Reaction: step.reaction}
+		ctx := session(r, step.clientID)
+		var response *proto.ReactionResponse
+		var err error
+		if step.removed {
+			response, err = s.RemoveReaction(ctx, req)
+		} else {
+			response, err = s.AddReaction(ctx, req)
+		}
+		if status.Code(err) != step.code {
+			t.Fatalf("%s: %v, want %v", step.name, err, step.code)
+		}
+		if recorded := len(s.history.events) > before; recorded != step.recorded {
+			t.Fatalf("%s: recorded=%v, want %v", step.name, recorded, step.recorded)
+		}
+		if err != nil {
+			continue
+		}
+		if len(response.Reactions) != len(step.counts) {
+			t.Fatalf("%s: counts %v, want %v", step.name, response.Reactions, step.counts)
+		}
+		for reaction, count := range step.counts {
+			if response.Reactions[reaction] != count {
+				t.Fatalf("%s: counts %v, want %v", step.name, response.Reactions, step.counts)
+			}
+		}
+	}
+	if got := r.messages["m1"].current.GetReactions()["👍"]; got != 1 {
+		t.Fatalf("the message reads with %d 👍, want 1", got)
+	}
+}
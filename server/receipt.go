@@ -0,0 +1,133 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxReceipts is how many message IDs one ReceiptRequest may carry
+const maxReceipts = 256
+
+// receipt is who got and read one message, kept in memory and in cluster snapshots only
+type receipt struct {
+	author    string
+	delivered map[string]bool
+	read      map[string]bool
+}
+
+// Acknowledge takes the receipts of a participant for messages of others
+func (s *ChitChatServer) Acknowledge(ctx context.Context, req *proto.ReceiptRequest) (*proto.ReceiptResponse, error) {
+	err := s.update(func() (*proto.ChatCommand, error) {
+		r, err := s.room(req.GetRoom())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+			return nil, err
+		}
+		if len(req.GetDelivered())+len(req.GetRead()) > maxReceipts {
+			return nil, status.Errorf(codes.InvalidArgument, "at most %d message IDs per request", maxReceipts)
+		}
+		if s.cluster != nil {
+			req.Room = r.name
+			return &proto.ChatCommand{Command: &proto.ChatCommand_Receipt{Receipt: req}}, nil
+		}
+		s.acknowledge(r, req)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ReceiptResponse{Ack: true}, nil
+}
+
+// acknowledge records receipts and tells each author whose message got a new one.
+// Unknown messages and the authors own receipts are skipped. Must be called with s.mutex held
+func (s *ChitChatServer) acknowledge(r *room, req *proto.ReceiptRequest) {
+	clientID := req.GetClientId()
+	changed := make(map[string]*receipt)
+	note := func(messageID string, read bool) {
+		rc := s.receiptOf(r, messageID, clientID)
+		if rc == nil || rc.read[clientID] || (!read && rc.delivered[clientID]) {
+			return
+		}
+		rc.delivered[clientID] = true
+		if read {
+			rc.read[clientID] = true
+		}
+		changed[messageID] = rc
+	}
+	for _, messageID := range req.GetDelivered() {
+		note(messageID, false)
+	}
+	for _, messageID := range req.GetRead() {
+		note(messageID, true)
+	}
+
+	for messageID, rc := range changed {
+		s.broadcast(r, &proto.BroadCast{
+			Type:      proto.BroadCast_RECEIPT,
+			ClientId:  rc.author,
+			Timestamp: r.clock.Now(),
+			Room:      r.name,
+			MessageId: messageID,
+			Delivered: int32(len(rc.delivered)),
+			Read:      int32(len(rc.read)),
+		}, r.sessionsOf(rc.author))
+	}
+}
+
+// receiptOf returns the receipts of a message clientID may acknowledge, nil if they may not.
+// That is checked on every call, whoever acknowledged before. The message is looked up in
+// the index of the room, so a request full of IDs does not go through the history. Must be
+// called with s.mutex held
+func (s *ChitChatServer) receiptOf(r *room, messageID, clientID string) *receipt {
+	target, deleted := s.message(r, messageID)
+	if target == nil || deleted || target.GetClientId() == clientID || !visibleTo(target, clientID) {
+		return nil
+	}
+	rc, ok := r.receipts[messageID]
+	if !ok {
+		rc = &receipt{author: target.GetClientId(), delivered: make(map[string]bool), read: make(map[string]bool)}
+		r.receipts[messageID] = rc
+	}
+	return rc
+}
+
+// GetReceipts returns who got and who read a message
+func (s *ChitChatServer) GetReceipts(ctx context.Context, req *proto.GetReceiptsRequest) (*proto.GetReceiptsResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	r, err := s.room(req.GetRoom())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+		return nil, err
+	}
+	messageID := req.GetMessageId()
+	if target, _ := s.message(r, messageID); target == nil || !visibleTo(target, req.GetClientId()) {
+		return nil, status.Errorf(codes.NotFound, "no message %q in room %s", messageID, r.name)
+	}
+
+	response := &proto.GetReceiptsResponse{}
+	if rc, ok := r.receipts[messageID]; ok {
+		response.DeliveredTo = names(rc.delivered)
+		response.ReadBy = names(rc.read)
+	}
+	return response, nil
+}
+
+// names returns the participants in a set of receipts, sorted
+func names(set map[string]bool) []string {
+	var ids []string
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"slices"
+	"testing"
+)
+
+func TestReceiptVisibility(t *testing.T) {
+	s := newTestServer(t)
+	r := s.rooms[defaultRoom]
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "hi", MessageId: "public"})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_DIRECT, ClientId: "alice", Recipient: "carol", Message: "psst", MessageId: "private"})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "oops", MessageId: "deleted"})
+
+	// The steps run in order, receipts given before stay
+	steps := []struct {
+		name      string
+		clientID  string
+		messageID string
+		read      bool
+		delivered []string // who got the message afterwards
+	}{
+		{"public message", "bob", "public", false, []string{"bob"}},
+		{"own message", "alice", "public", true, []string{"bob"}},
+		{"second participant", "carol", "public", false, []string{"carol", "bob"}},
+		{"recipient of a private message", "carol", "private", false, []string{"carol"}},
+		{"someone else after the recipient", "bob", "private", true, []string{"carol"}},
+		{"unknown message", "bob", "missing", false, nil},
+		{"before the delete", "bob", "deleted", false, []string{"bob"}},
+		{"after the delete", "carol", "deleted", false, []string{"bob"}},
+	}
+	for _, step := range steps {
+		if step.name == "after the delete" {
+			s.deliverAmend(r, r.messages["deleted"].message, &proto.BroadCast{Type: proto.BroadCast_DELETE, ClientId: "alice", MessageId: "deleted"})
+		}
+		req := &proto.ReceiptRequest{ClientId: step.clientID, Room: defaultRoom}
+		if step.read {
+			req.Read = []string{step.messageID}
+		} else {
+			req.Delivered = []string{step.messageID}
+		}
+		s.acknowledge(r, req)
+
+		var got []string
+		if rc, ok := r.receipts[step.messageID]; ok {
+			got = names(rc.delivered)
+		}
+		want := slices.Sorted(slices.Values(step.delivered))
+		if !slices.Equal(got, want) {
+			t.Fatalf("%s: delivered to %v, want %v", step.name, got, want)
+		}
+	}
+}
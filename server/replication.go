@@ -0,0 +1,284 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	followerQueueSize    = 1024        // events a slow backup may lag behind before it has to catch up again
+	replicationHeartbeat = time.Second // how often the primary tells quiet backups it is alive
+	followRetry          = 500 * time.Millisecond
+)
+
+// follower is a backup streaming the primary's state, with its own bounded queue
+type follower struct {
+	id     string
+	queue  chan *proto.ReplicationEvent
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (f *follower) close() {
+	f.once.Do(func() { close(f.closed) })
+}
+
+// replicator serves the internal Replication service of a ChitChatServer
+type replicator struct {
+	proto.UnimplementedReplicationServer
+	chat *ChitChatServer
+}
+
+// Follow streams the history the backup is missing, the clock of every room and then
+// every change as it happens. Only the primary can be followed
+func (rep *replicator) Follow(req *proto.FollowRequest, stream proto.Replication_FollowServer) error {
+	s := rep.chat
+
+	s.mutex.Lock()
+	if s.primary != "" {
+		s.mutex.Unlock()
+		return status.Errorf(codes.FailedPrecondition, "not the primary, follow %s", s.primary)
+	}
+	//Take the catch-up and register for live events in one go, so nothing falls in between
+	from := int(req.GetFrom())
+	if from < 0 || from > len(s.history.events) {
+		from = len(s.history.events)
+	}
+	var catchUp []*proto.ReplicationEvent
+	for i, broadcast := range s.history.events[from:] {
+		catchUp = append(catchUp, &proto.ReplicationEvent{
+			Index:     int64(from + i + 1),
+			Broadcast: broadcast,
+			Room:      roomOf(broadcast),
+			Clock:     broadcast.GetTimestamp(),
+		})
+	}
+	for _, r := range s.rooms {
+		catchUp = append(catchUp, s.clockEvent(r))
+	}
+	f := &follower{
+		id:     req.GetBackupId(),
+		queue:  make(chan *proto.ReplicationEvent, followerQueueSize),
+		closed: make(chan struct{}),
+	}
+	s.followers[f] = true
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.followers, f)
+		s.mutex.Unlock()
+		log.Printf("Server REPLICATION: backup %s stopped following", f.id)
+	}()
+
+	log.Printf("Server REPLICATION: backup %s follows from event %d", f.id, from)
+	for _, event := range catchUp {
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(replicationHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-f.closed:
+			return status.Error(codes.Aborted, "dropped by the primary, follow again")
+		case <-ticker.C:
+			if err := stream.Send(&proto.ReplicationEvent{}); err != nil {
+				return err
+			}
+		case event := <-f.queue:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// replicate queues an event for every backup. A backup whose queue is full is dropped,
+// it follows again from its own history. Must be called with s.mutex held
+func (s *ChitChatServer) replicate(event *proto.ReplicationEvent) {
+	for f := range s.followers {
+		select {
+		case f.queue <- event:
+		default:
+			log.Printf("Server REPLICATION: backup %s fell %d events behind, dropping it", f.id, followerQueueSize)
+			delete(s.followers, f)
+			f.close()
+		}
+	}
+}
+
+// replicateClock tells the backups where a rooms clock is when it moved without a
+// broadcast, or the room is new. Must be called with s.mutex held
+func (s *ChitChatServer) replicateClock(r *room) {
+	s.replicate(s.clockEvent(r))
+}
+
+// clockEvent is a ReplicationEvent without a broadcast. Must be called with s.mutex held
+func (s *ChitChatServer) clockEvent(r *room) *proto.ReplicationEvent {
+	return &proto.ReplicationEvent{
+		Index: int64(len(s.history.events)),
+		Room:  r.name,
+		Clock: r.clock.Now(),
+	}
+}
+
+// apply brings a backup up to date with one event from the primary
+func (s *ChitChatServer) apply(event *proto.ReplicationEvent) {
+	if event.GetRoom() == "" {
+		return // heartbeat
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	r, ok := s.rooms[event.GetRoom()]
+	if !ok {
+		r = newRoom(event.GetRoom(), 0, s.vectorMode)
+		s.rooms[r.name] = r
+	}
+	// Skip what we already have, e.g. after following again
+	if broadcast := event.GetBroadcast(); broadcast != nil && event.GetIndex() > int64(len(s.history.events)) {
+		if err := s.history.append(broadcast); err != nil {
+			log.Printf("Server HISTORY_ERROR: failed to persist replicated broadcast at logical time %d: %v", broadcast.Timestamp, err)
+		}
+		//The same bookkeeping as on the primary, so a retry after the takeover is spotted
+		s.note(r, broadcast)
+		if r.vector != nil {
+			r.vector.Merge(broadcast.GetVector())
+		}
+	}
+	r.clock.Advance(event.GetClock())
+}
+
+// follow keeps this backup in sync with the first primary it finds in addrs, tried in order.
+// A server in the list that answers but is a backup itself counts as alive, it is about to
+// take over. Once none of them was reachable for takeoverAfter this server becomes the primary
+func (s *ChitChatServer) follow(addrs []string, creds credentials.TransportCredentials, self string, takeoverAfter time.Duration) {
+	conns := make([]proto.ReplicationClient, len(addrs))
+	for i, addr := range addrs {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			log.Fatalf("Server STARTUP_ERROR: bad primary address %s: %v", addr, err)
+		}
+		defer conn.Close()
+		conns[i] = proto.NewReplicationClient(conn)
+	}
+
+	lastContact := time.Now()
+	for {
+		for i, addr := range addrs {
+			alive, err := s.followOnce(conns[i], addr, self, takeoverAfter)
+			if alive {
+				lastContact = time.Now()
+			}
+			// Unreachable servers and backups are expected while we wait, do not fill the log with them
+			if code := status.Code(err); err != nil && code != codes.Unavailable && code != codes.FailedPrecondition {
+				log.Printf("Server REPLICATION: %s: %v", addr, status.Convert(err).Message())
+			}
+		}
+
+		if time.Since(lastContact) >= takeoverAfter {
+			s.mutex.Lock()
+			log.Printf("Server PROMOTED: no primary in %s for %s, taking over at history event %d",
+				strings.Join(addrs, ","), takeoverAfter, len(s.history.events))
+			s.primary = ""
+			s.mutex.Unlock()
+			return
+		}
+		time.Sleep(followRetry)
+	}
+}
+
+// followOnce follows one server until its stream ends. alive tells whether it answered at all
+func (s *ChitChatServer) followOnce(rpc proto.ReplicationClient, addr, self string, silence time.Duration) (alive bool, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.mutex.Lock()
+	from := int64(len(s.history.events))
+	s.mutex.Unlock()
+	stream, err := rpc.Follow(ctx, &proto.FollowRequest{BackupId: self, From: from})
+	if err != nil {
+		return false, err
+	}
+
+	//A primary that goes quiet is as good as gone, even if the connection looks fine
+	watchdog := time.AfterFunc(silence, cancel)
+	defer watchdog.Stop()
+
+	following := false
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			// A backup answering FailedPrecondition is alive, it just is not the primary (yet)
+			return following || status.Code(err) == codes.FailedPrecondition, err
+		}
+		if !following {
+			following = true
+			s.mutex.Lock()
+			s.primary = addr
+			s.mutex.Unlock()
+			log.Printf("Server REPLICATION: following primary %s from event %d", addr, from)
+		}
+		watchdog.Reset(silence)
+		s.apply(event)
+	}
+}
+
+// backupError is non-nil while this server is a backup. It is Unavailable,
+// so clients move on to the next server in their list
+func (s *ChitChatServer) backupError() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.primary == "" {
+		return nil
+	}
+	return status.Errorf(codes.Unavailable, "this server is a backup of %s", s.primary)
+}
+
+// unaryGate turns client calls away while this server is a backup, and calls to the
+// internal services from anyone but our peers
+func (s *ChitChatServer) unaryGate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.gate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamGate is unaryGate for streams
+func (s *ChitChatServer) streamGate(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.gate(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+func (s *ChitChatServer) gate(ctx context.Context, method string) error {
+	if clientCall(method) {
+		return s.backupError()
+	}
+	if _, err := s.authenticatePeer(ctx); err != nil {
+		log.Printf("Server PEER_REJECTED: %s: %v", method, status.Convert(err).Message())
+		return err
+	}
+	return nil
+}
+
+// clientCall tells whether a method belongs to the client facing ChitChat service
+func clientCall(method string) bool {
+	return strings.HasPrefix(method, fmt.Sprintf("/%s/", proto.ChitChat_ServiceDesc.ServiceName))
+}
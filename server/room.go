@@ -0,0 +1,150 @@
+package main
+
+import (
+	"ChitChat/clock"
+	proto "ChitChat/grpc"
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultRoom is used when a request does not name a room, and always exists
+const defaultRoom = "general"
+
+// room is one chat channel with its own participants and logical clocks
+type room struct {
+	name         string
+	subscribers  map[string]*subscriber // session -> subscriber with its outbound queue, one user can have several
+	clock        *clock.Lamport
+	vector       *clock.Vector              // merged view of every senders vector clock, nil in lamport mode
+	present      map[string]map[string]bool // cluster mode: participant -> node runs they are subscribed on
+	typing       map[string]*typist         // participants who are or were just typing, never persisted
+	participants map[string]*participant    // everyone whose JOIN this server saw, with their status
+	receipts     map[string]*receipt        // message ID -> who got and read it
+	last         int64                      // Lamport time of the last broadcast that went to everybody
+	messages     map[string]*posted         // message ID -> the message with that ID
+}
+
+func newRoom(name string, start int64, vectorMode bool) *room {
+	r := &room{
+		name:         name,
+		subscribers:  make(map[string]*subscriber),
+		clock:        clock.NewLamport(start),
+		present:      make(map[string]map[string]bool),
+		typing:       make(map[string]*typist),
+		participants: make(map[string]*participant),
+		receipts:     make(map[string]*receipt),
+		messages:     make(map[string]*posted),
+	}
+	if vectorMode {
+		r.vector = clock.NewVector()
+	}
+	return r
+}
+
+// members returns the IDs of everyone subscribed, on any node in cluster mode,
+// sorted and without duplicates
+func (r *room) members() []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(r.subscribers))
+	for _, sub := range r.subscribers {
+		if !seen[sub.id] {
+			seen[sub.id] = true
+			ids = append(ids, sub.id)
+		}
+	}
+	for id := range r.present {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sessionsOf returns every session (device) a participant has in the room
+func (r *room) sessionsOf(clientID string) map[string]*subscriber {
+	sessions := make(map[string]*subscriber)
+	for session, sub := range r.subscribers {
+		if sub.id == clientID {
+			sessions[session] = sub
+		}
+	}
+	return sessions
+}
+
+// online tells whether a participant still has at least one session in the room
+func (r *room) online(clientID string) bool {
+	return len(r.sessionsOf(clientID)) > 0
+}
+
+// roomName maps the empty name used by older clients to the default room
+func roomName(name string) string {
+	if name == "" {
+		return defaultRoom
+	}
+	return name
+}
+
+// roomOf returns the room a persisted broadcast belongs to
+func roomOf(broadcast *proto.BroadCast) string {
+	return roomName(broadcast.GetRoom())
+}
+
+// CreateRoom opens a new empty room
+func (s *ChitChatServer) CreateRoom(ctx context.Context, req *proto.CreateRoomRequest) (*proto.CreateRoomResponse, error) {
+	name := strings.TrimSpace(req.GetName())
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "room name required")
+	}
+
+	err := s.update(func() (*proto.ChatCommand, error) {
+		if _, exists := s.rooms[name]; exists {
+			return nil, status.Errorf(codes.AlreadyExists, "room %q already exists", name)
+		}
+		//Creating a room twice in the cluster log only creates it once
+		if s.cluster != nil {
+			return &proto.ChatCommand{Command: &proto.ChatCommand_CreateRoom{CreateRoom: name}}, nil
+		}
+		s.rooms[name] = newRoom(name, 0, s.vectorMode)
+		//Recorded, so the room is still there after a restart and on the backups
+		s.record(s.rooms[name], &proto.BroadCast{Type: proto.BroadCast_ROOM_CREATED})
+
+		log.Printf("Server ROOM_CREATED: %s", name)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.CreateRoomResponse{Ack: true}, nil
+}
+
+// ListRooms returns every room with the number of participants in it
+func (s *ChitChatServer) ListRooms(ctx context.Context, req *proto.ListRoomsRequest) (*proto.ListRoomsResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rooms := make([]*proto.RoomInfo, 0, len(s.rooms))
+	for name, r := range s.rooms {
+		rooms = append(rooms, &proto.RoomInfo{Name: name, Members: int32(len(r.members()))})
+	}
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+	return &proto.ListRoomsResponse{Rooms: rooms}, nil
+}
+
+// ListMembers returns the participants currently subscribed to a room
+func (s *ChitChatServer) ListMembers(ctx context.Context, req *proto.ListMembersRequest) (*proto.ListMembersResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, err := s.room(req.GetRoom())
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ListMembersResponse{Members: r.members()}, nil
+}
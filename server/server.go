@@ -2,163 +2,547 @@ package main
 
 import (
 	proto "ChitChat/grpc"
+	"ChitChat/raft"
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
+// sessionHeader is the response header Subscribe uses to hand out the session ID
+const sessionHeader = "chitchat-session"
+
 var (
-	port = flag.Int("port", 50051, "The server port")
+	port      = flag.Int("port", 50051, "The server port")
+	queueSize = flag.Int("queue-size", 256, "Outbound queue size per subscriber")
+	overflow  = flag.String("overflow", "drop-oldest", "What to do when a subscribers queue is full: drop-oldest, drop-newest or disconnect")
+	historyDB = flag.String("history", "chitchat.log", "File the broadcast history is appended to (empty keeps it in memory only)")
+	clockMode = flag.String("clock", "lamport", "Logical clock carried by broadcasts: lamport or vector (vector also keeps the Lamport timestamp)")
+	dedupSize = flag.Int("dedup-window", 10000, "How many recent message IDs are remembered, so retried messages are only posted once")
+	certFile  = flag.String("cert", "", "TLS certificate (PEM), enables TLS together with -key")
+	keyFile   = flag.String("key", "", "TLS private key (PEM)")
+	caFile    = flag.String("ca", "", "CA bundle (PEM) for client certificates, enables mutual TLS where the certificate CN is the participant ID")
+
+	shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "How long to wait for queues to drain on SIGINT/SIGTERM")
+	reconnectHint   = flag.Duration("reconnect-hint", 5*time.Second, "How long clients are told to wait before reconnecting after a shutdown")
+	heartbeat       = flag.Duration("heartbeat", 5*time.Second, "How often subscribers get a HEARTBEAT (0 turns heartbeats off)")
+	heartbeatMisses = flag.Int("heartbeat-misses", 3, "Heartbeats a subscriber may miss before it is evicted")
+	admins          = flag.String("admins", "", "Comma separated participant IDs that may edit and delete everyones messages")
+	configFile      = flag.String("config", "", "JSON config file, e.g. for the message validation (see README)")
+	idleAfter       = flag.Duration("idle-after", 5*time.Minute, "Participants who neither write nor type for this long are listed as idle (0 never)")
+	backupOf        = flag.String("backup-of", "", "Comma separated server addresses to replicate from, in order. Makes this server a backup that takes over once none of them is reachable")
+	takeoverAfter   = flag.Duration("takeover-after", 3*time.Second, "How long a backup waits without a primary before it takes over")
+	peerIDs         = flag.String("peer-ids", "", "Comma separated certificate CNs of the servers that may use the internal replication, cluster and federation services")
+	insecurePeers   = flag.Bool("insecure-peers", false, "Let anyone use the internal services, for plaintext setups where servers cannot be told apart from clients")
+
+	raftID        = flag.String("raft-id", "", "Run as this node of a Raft cluster, every node serves clients from the same chat log")
+	raftPeers     = flag.String("raft-peers", "", "Comma separated id=host:port of every node, this one included, to start a new cluster with")
+	raftJoin      = flag.String("raft-join", "", "Address of a running cluster node to join through, instead of -raft-peers")
+	raftAddr      = flag.String("raft-addr", "", "Address the other nodes reach this one at (default localhost:<port>, or the own entry in -raft-peers)")
+	raftDir       = flag.String("raft-dir", "", "Directory for the Raft log and snapshots (empty keeps them in memory only)")
+	snapshotEvery = flag.Uint64("raft-snapshot-every", 1000, "Compact the Raft log into a snapshot after this many entries")
+	raftLeave     = flag.Bool("raft-leave", false, "Remove this node from the cluster when it shuts down")
+
+	serverName = flag.String("server-name", "", "Name of this server in federated identities (user@name), enables federation")
+	federateTo = flag.String("federate", "", "Comma separated addresses of servers to federate with, needs -server-name")
 )
 
 // server implements the gRPC service defined in our protobuff
 type ChitChatServer struct {
 	proto.UnimplementedChitChatServer
 
-	mutex       sync.Mutex                                // locking should be possible for clocking
-	subscribers map[string]proto.ChitChat_SubscribeServer // clientID -> stream
-	timestamp   int64
+	mutex    sync.Mutex       // locking should be possible for clocking
+	rooms    map[string]*room // room name -> participants and clock of that room
+	history  *history         // every broadcast of every room, replayed to late joiners
+	sessions int64            // counter for session IDs
+	closing  bool             // shutting down, no new subscribers
+	dedup    *dedupWindow     // recently published messages, to spot retries
+
+	peerIDs       map[string]bool // certificate CNs of the servers allowed to use the internal services
+	insecurePeers bool            // anyone may use the internal services
+
+	primary   string             // address of the primary while this server is a backup, empty on the primary
+	followers map[*follower]bool // backups streaming our state
+	cluster   *raft.Node         // set in cluster mode, every change goes through its log
+	node      string             // this run of the cluster node, stamped on everything it commits
+	proposed  atomic.Int64       // counter for the proposals of this run
+	proposals *dedupWindow       // recently applied proposals, keyed by node run and number
+
+	name  string             // federation: our server name, empty when federation is off
+	seen  map[string]int64   // federation: origin server -> highest event ID we have from it
+	epoch int64              // federation: start time of this run, our event IDs go on above it
+	peers map[*peerLink]bool // federation: linked servers
+
+	admins    map[string]bool // may edit and delete messages of others
+	idleAfter time.Duration   // participants who did nothing for this long are IDLE
+
+	validators []validator // every published or edited text goes through them in order
+
+	queueSize  int
+	overflow   overflowPolicy
+	vectorMode bool
+}
+
+// newChitChatServer restores the rooms and their logical clocks from the persisted history
+func newChitChatServer(history *history, queueSize int, overflow overflowPolicy, vectorMode bool, dedupSize int) *ChitChatServer {
+	s := &ChitChatServer{
+		rooms:      make(map[string]*room),
+		dedup:      newDedupWindow(dedupSize),
+		proposals:  newDedupWindow(dedupSize),
+		followers:  make(map[*follower]bool),
+		seen:       make(map[string]int64),
+		peers:      make(map[*peerLink]bool),
+		admins:     make(map[string]bool),
+		history:    history,
+		queueSize:  queueSize,
+		overflow:   overflow,
+		vectorMode: vectorMode,
+	}
+
+	latest := map[string]int64{defaultRoom: 0}
+	for _, event := range history.events {
+		name := roomOf(event)
+		if event.GetTimestamp() > latest[name] {
+			latest[name] = event.GetTimestamp()
+		}
+	}
+	for name, timestamp := range latest {
+		s.rooms[name] = newRoom(name, timestamp, vectorMode)
+	}
+	for _, event := range history.events {
+		r := s.rooms[roomOf(event)]
+		if event.GetRecipient() == "" {
+			r.last = event.GetTimestamp()
+		}
+		r.index(event)
+	}
+	s.dedup.load(history.events)
+	for _, event := range history.events {
+		if origin := event.GetOrigin(); origin != "" && event.GetEventId() > s.seen[origin] {
+			s.seen[origin] = event.GetEventId()
+		}
+	}
+	if vectorMode {
+		for _, event := range history.events {
+			s.rooms[roomOf(event)].vector.Merge(event.GetVector())
+		}
+	}
+	return s
 }
 
 // Subscribe handles new client connection using server-side streaming
 // This method runs the entire duration of a clients connection
 func (s *ChitChatServer) Subscribe(req *proto.SubscribeRequest, stream proto.ChitChat_SubscribeServer) error {
+	r, sub, err := s.attach(stream.Context(), req, stream)
+	if err != nil {
+		return err
+	}
+	s.serve(stream.Context(), r, sub, nil)
+	return nil
+}
+
+// attach checks who is subscribing, gives the stream a session and registers it in the room.
+// Shared by Subscribe and Chat
+func (s *ChitChatServer) attach(ctx context.Context, req *proto.SubscribeRequest, stream outbound) (*room, *subscriber, error) {
 	clientID := req.GetId()
+	//Under mutual TLS the certificate decides who you are
+	if cn, ok := peerID(ctx); ok {
+		if clientID == "" {
+			clientID = cn
+		} else if clientID != cn {
+			return nil, nil, status.Errorf(codes.PermissionDenied, "client certificate is for %s, not %s", cn, clientID)
+		}
+	}
 	if clientID == "" {
-		return errors.New("client_id required")
+		return nil, nil, errors.New("client_id required")
+	}
+	if s.name != "" && strings.Contains(clientID, "@") {
+		return nil, nil, status.Error(codes.InvalidArgument, "client IDs may not contain @, it names the server of federated participants")
 	}
 
 	s.mutex.Lock()
+	if s.closing {
+		s.mutex.Unlock()
+		return nil, nil, status.Error(codes.Unavailable, "server is shutting down")
+	}
+	r, err := s.room(req.GetRoom())
+	if err != nil {
+		s.mutex.Unlock()
+		return nil, nil, err
+	}
+	s.sessions++
+	session := fmt.Sprintf("%s#%d", clientID, s.sessions)
+	s.mutex.Unlock()
 
-	if s.subscribers == nil {
-		s.subscribers = make(map[string]proto.ChitChat_SubscribeServer)
+	token, err := newToken()
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "failed to create session token: %v", err)
 	}
-	//Register the clients stream for recieving broadcasts
-	//Update logical clock
-	s.subscribers[clientID] = stream
-	s.timestamp++
-	currentTime := s.timestamp
 
-	// Create and send JOIN broadcast to all clients
-	broadcast := &proto.BroadCast{
-		Type:      proto.BroadCast_JOIN,
-		ClientId:  clientID,
-		Timestamp: currentTime,
-		Message:   "",
+	//Tell the client which session (device) this stream is, and the token it has to
+	//send with Publish and Leave to prove it
+	if err := stream.SendHeader(metadata.Pairs(sessionHeader, session, tokenHeader, token)); err != nil {
+		return nil, nil, err
 	}
 
-	// Send JOIN message to ALL clients including the new one
-	for id, subscriber := range s.subscribers {
-		if err := subscriber.Send(broadcast); err != nil {
-			log.Printf("Failed to send JOIN broadcast to %s: %v", id, err)
+	var sub *subscriber
+	err = s.update(func() (*proto.ChatCommand, error) {
+		//Pick the history to replay before live broadcasts, taken under the lock
+		//so nothing falls between the replay and the queue
+		var replay []*proto.BroadCast
+		if req.GetLastN() > 0 || req.GetSinceTimestamp() > 0 {
+			replay = s.history.replay(r.name, clientID, req.GetSinceTimestamp(), int(req.GetLastN()))
 		}
+		sub = newSubscriber(clientID, session, token, stream, s.queueSize, replay)
+
+		//Register the clients stream for recieving broadcasts.
+		//Only the first session of a participant is a JOIN, more devices join quietly
+		firstSession := !r.online(clientID)
+		r.subscribers[session] = sub
+		if firstSession && s.cluster != nil {
+			//The JOIN reaches this session like everyone else once the cluster committed it
+			return eventCommand(&proto.BroadCast{Type: proto.BroadCast_JOIN, ClientId: clientID, Room: r.name}), nil
+		} else if firstSession {
+			//Update logical clock
+			currentTime := r.clock.Tick()
+
+			// Queue JOIN message for ALL clients in the room including the new one
+			s.emit(r, &proto.BroadCast{
+				Type:      proto.BroadCast_JOIN,
+				ClientId:  clientID,
+				Timestamp: currentTime,
+				Message:   "",
+			})
+			log.Printf("Participant %s joined Chit Chat room %s at logical time %d", clientID, r.name, currentTime)
+		} else {
+			log.Printf("Participant %s attached another session %s to room %s", clientID, session, r.name)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		s.mutex.Lock()
+		s.evict(r, sub, "")
+		s.mutex.Unlock()
+		return nil, nil, err
 	}
+	return r, sub, nil
+}
 
-	s.mutex.Unlock()
-
-	log.Printf("Participant %s joined Chit Chat at logical time %d", clientID, currentTime)
-
-	//WAIT HERE, until the client disconnects or is cancelled
-	<-stream.Context().Done()
+// serve drains the subscribers queue until the client disconnects, is kicked out, the stream
+// breaks or done fires, and then removes the session from the room
+func (s *ChitChatServer) serve(ctx context.Context, r *room, sub *subscriber, done <-chan error) {
+	// Drain the outbound queue in its own goroutine
+	sendErr := make(chan error, 1)
+	go func() { sendErr <- sub.run() }()
+
+	//WAIT HERE, until the client disconnects, is kicked out or the stream breaks
+	reason := ""
+	select {
+	case <-ctx.Done():
+	case <-sub.closed:
+	case <-done:
+	case err := <-sendErr:
+		if err != nil {
+			log.Printf("Failed to send broadcast to %s: %v", sub.session, err)
+			//The connection is dead, not closed by the client
+			reason = "timeout"
+		}
+	}
 
 	//Clean up client subscribtion
-	s.removeSubscriber(clientID)
-	log.Printf("Participant %s disconnected at logical time %d", clientID, s.timestamp)
-
-	return nil
+	s.removeSubscriber(r, sub, reason)
+	log.Printf("Session %s disconnected from room %s", sub.session, r.name)
 }
 
 // Publish handles chat meesages from clients
 func (s *ChitChatServer) Publish(ctx context.Context, req *proto.PublishRequest) (*proto.PublishResponse, error) {
-	clientID := req.GetClientId()
-	message := req.GetText()
-
-	//Message validation - reject if its over 128char long
-	if len(message) > 128 {
-		return nil, status.Error(codes.InvalidArgument, "Message was too long")
+	var response *proto.PublishResponse
+	err := s.update(func() (*proto.ChatCommand, error) {
+		r, err := s.room(req.GetRoom())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+			return nil, err
+		}
+		var command *proto.ChatCommand
+		response, command, err = s.publish(r, req)
+		return command, err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return response, nil
+}
 
-	//Update logical clock
-	s.mutex.Lock()
-	s.timestamp++
-	s.mutex.Unlock()
+// publish validates a message from an authorized sender and queues it for the room,
+// or only for the recipient. In cluster mode it returns the command to commit instead.
+// Must be called with s.mutex held
+func (s *ChitChatServer) publish(r *room, req *proto.PublishRequest) (*proto.PublishResponse, *proto.ChatCommand, error) {
+	clientID := req.GetClientId()
+	message, rejected := validate(s.validators, req.GetText())
+	if rejected != nil {
+		log.Printf("Server Publish rejected: room=%s from=%s %v", r.name, clientID, rejected)
+		return &proto.PublishResponse{Ack: false, Error: rejected.reason, ErrorCode: rejected.code}, nil, nil
+	}
+	//What is posted is the text as the validation left it
+	req.Text = message
+	if req.GetMessageId() == "" {
+		id, err := newMessageID()
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Internal, "failed to create message ID: %v", err)
+		}
+		req.MessageId = id
+	}
+	//A retry of a message we already posted is acknowledged again, but not posted twice
+	target, _ := s.message(r, req.GetMessageId())
+	if s.dedup.contains(clientID, req.GetMessageId()) || target.GetClientId() == clientID {
+		log.Printf("Server Publish duplicate: room=%s from=%s message_id=%s", r.name, clientID, req.GetMessageId())
+		return &proto.PublishResponse{Ack: true, MessageId: req.GetMessageId()}, nil, nil
+	}
+	//The ID names one message of the room, edits, replies and reactions find it by the ID alone
+	if target != nil {
+		log.Printf("Server Publish rejected: room=%s from=%s message_id=%s is taken by %s", r.name, clientID, req.GetMessageId(), target.GetClientId())
+		return nil, nil, status.Errorf(codes.AlreadyExists, "message ID %s is already taken in room %s", req.GetMessageId(), r.name)
+	}
+	if err := s.checkParent(r, req); err != nil {
+		return nil, nil, err
+	}
+	//Sending the message ends typing it
+	s.setTyping(r, clientID, false)
+	if s.cluster != nil {
+		return s.publishCluster(r, req)
+	}
 
-	//Create broadcast message to send to all clients
-	broadcast := &proto.BroadCast{
+	if req.GetRecipient() != "" {
+		response, err := s.publishDirect(r, req)
+		return response, nil, err
+	}
+	//Merge the senders clock and queue the message for the room in one go,
+	//so broadcasts are queued in timestamp order
+	currentTime := r.clock.Merge(req.GetTimestamp())
+	s.emit(r, &proto.BroadCast{
 		Type:      proto.BroadCast_CHAT,
 		ClientId:  clientID,
 		Message:   message,
-		Timestamp: s.timestamp,
-	}
-
-	// Send message to ALL clients including the new one
-	s.mutex.Lock()
-	for _, subscriber := range s.subscribers {
-		if err := subscriber.Send(broadcast); err != nil {
-			println("Failed to send to subscriber:", err.Error())
-		}
-	}
-	s.mutex.Unlock()
-	log.Printf("Server Publish received: from=%s logical_time=%d content=%q", clientID, s.timestamp, message)
+		Timestamp: currentTime,
+		Vector:    senderVector(r, clientID, req.GetVector()),
+		MessageId: req.GetMessageId(),
+		ParentId:  req.GetParentId(),
+	})
+	log.Printf("Server Publish received: room=%s from=%s logical_time=%d message_id=%s content=%q", r.name, clientID, currentTime, req.GetMessageId(), message)
 
-	return &proto.PublishResponse{Ack: true}, nil
+	return &proto.PublishResponse{Ack: true, MessageId: req.GetMessageId()}, nil, nil
 }
 
 // Leave handles client disconnections
 func (s *ChitChatServer) Leave(ctx context.Context, req *proto.LeaveRequest) (*proto.LeaveResponse, error) {
 	clientID := req.GetClientId()
 
-	//Removes client from active subscriber
 	s.mutex.Lock()
-	_, exists := s.subscribers[clientID]
-	if exists {
-		delete(s.subscribers, clientID)
-		s.timestamp++
+	defer s.mutex.Unlock()
+	r, err := s.room(req.GetRoom())
+	if err != nil {
+		return nil, err
 	}
-	currentTime := s.timestamp
 
-	//Create leave message to send to all clients
-	broadcast := &proto.BroadCast{
-		Type:      proto.BroadCast_LEAVE,
-		ClientId:  clientID,
-		Timestamp: currentTime,
+	if _, err := authorize(ctx, r, clientID); err != nil {
+		return nil, err
 	}
 
-	// Send leave message to ALL clients including the new one
-	for _, subscriber := range s.subscribers {
-		if err := subscriber.Send(broadcast); err != nil {
-			println("Failed to leave:", err.Error())
+	//Removes the given session, or every session of the client if none is given
+	sessions := r.sessionsOf(clientID)
+	if session := req.GetSessionId(); session != "" {
+		sub, ok := sessions[session]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "session %q of %s is not in room %s", session, clientID, r.name)
 		}
+		sessions = map[string]*subscriber{session: sub}
 	}
-	s.mutex.Unlock()
-
-	log.Printf("Participant %s left Chit Chat at logical time %d", clientID, s.timestamp)
+	s.leave(r, clientID, sessions, req.GetTimestamp())
 
 	return &proto.LeaveResponse{Ack: true}, nil
 }
 
+// leave ends the given sessions of a participant, merging the clock they left at.
+// Must be called with s.mutex held
+func (s *ChitChatServer) leave(r *room, clientID string, sessions map[string]*subscriber, timestamp int64) {
+	wasOnline := len(sessions) > 0
+	for session, sub := range sessions {
+		delete(r.subscribers, session)
+		sub.close()
+	}
+	if s.cluster != nil {
+		// Other nodes may still have sessions of the participant, the log keeps count
+		if wasOnline && !r.online(clientID) {
+			s.commitAsync(&proto.ChatCommand{Command: &proto.ChatCommand_Event{Event: &proto.BroadCast{
+				Type:      proto.BroadCast_LEAVE,
+				ClientId:  clientID,
+				Timestamp: timestamp,
+				Room:      r.name,
+			}}})
+		}
+		return
+	}
+	currentTime := r.clock.Merge(timestamp)
+
+	// Queue leave message for all remaining clients in the room once the last session is gone
+	if wasOnline && !r.online(clientID) {
+		s.emit(r, &proto.BroadCast{
+			Type:      proto.BroadCast_LEAVE,
+			ClientId:  clientID,
+			Timestamp: currentTime,
+		})
+		log.Printf("Participant %s left Chit Chat room %s at logical time %d", clientID, r.name, currentTime)
+	} else {
+		s.replicateClock(r)
+	}
+}
+
 func main() {
 	flag.Parse()
-	addr := ":50051"
+	addr := fmt.Sprintf(":%d", *port)
+
+	policy, err := parseOverflowPolicy(*overflow)
+	if err != nil {
+		log.Fatalf("Server STARTUP_ERROR: %v", err)
+	}
+	if *clockMode != "lamport" && *clockMode != "vector" {
+		log.Fatalf("Server STARTUP_ERROR: unknown clock mode %q (use lamport or vector)", *clockMode)
+	}
+
+	if *raftID != "" && *backupOf != "" {
+		log.Fatalf("Server STARTUP_ERROR: -raft-id and -backup-of do not go together")
+	}
+	if *serverName != "" && (*raftID != "" || *backupOf != "") {
+		log.Fatalf("Server STARTUP_ERROR: federation does not go together with -raft-id or -backup-of")
+	}
+	if *federateTo != "" && *serverName == "" {
+		log.Fatalf("Server STARTUP_ERROR: -federate needs -server-name")
+	}
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Server STARTUP_ERROR: failed to read config: %v", err)
+	}
+	validators, err := config.Validation.chain()
+	if err != nil {
+		log.Fatalf("Server STARTUP_ERROR: bad validation config: %v", err)
+	}
+	//In cluster mode the Raft log and its snapshots are the history
+	historyPath := *historyDB
+	if *raftID != "" {
+		historyPath = ""
+	}
+	history, err := openHistory(historyPath)
+	if err != nil {
+		log.Fatalf("Server STARTUP_ERROR: failed to open history %s: %v", historyPath, err)
+	}
+	defer history.close()
 
 	//Create TCP listener on specified port
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		log.Fatalf("Server STARTUP_ERROR: failed to listen on %s: %v", addr, err)
 	}
-	//Creates server instance
-	grpcServer := grpc.NewServer()
+	//Creates server instance, with TLS if a certificate was given
+	var options []grpc.ServerOption
+	creds, err := serverCredentials(*certFile, *keyFile, *caFile)
+	if err != nil {
+		log.Fatalf("Server STARTUP_ERROR: %v", err)
+	}
+	if creds != nil {
+		options = append(options, creds)
+	} else {
+		log.Printf("Server STARTUP: no -cert given, serving plaintext")
+	}
+	//Transport keepalive pings find half-open TCP connections the streams would never notice
+	if *heartbeat > 0 {
+		options = append(options, grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    *heartbeat,
+			Timeout: time.Duration(*heartbeatMisses) * *heartbeat,
+		}))
+	}
+	chat := newChitChatServer(history, *queueSize, policy, *clockMode == "vector", *dedupSize)
+	if *admins != "" {
+		for _, admin := range strings.Split(*admins, ",") {
+			chat.admins[strings.TrimSpace(admin)] = true
+		}
+	}
+	chat.idleAfter = *idleAfter
+	chat.peerIDs = parsePeerIDs(*peerIDs)
+	chat.insecurePeers = *insecurePeers
+	if chat.insecurePeers {
+		log.Printf("Server STARTUP: -insecure-peers, anyone who reaches the port can use the internal services")
+	}
+	chat.validators = validators
+	//Backups turn clients away until they take over
+	options = append(options, grpc.UnaryInterceptor(chat.unaryGate), grpc.StreamInterceptor(chat.streamGate))
+	grpcServer := grpc.NewServer(options...)
 	//Register our service implementation with the gRPC server
-	proto.RegisterChitChatServer(grpcServer, &ChitChatServer{})
+	proto.RegisterChitChatServer(grpcServer, chat)
+	proto.RegisterReplicationServer(grpcServer, &replicator{chat: chat})
+	proto.RegisterFederationServer(grpcServer, &federator{chat: chat})
 
 	log.Printf("Server STARTUP: listening on %s", addr)
+	if *backupOf != "" {
+		primaries := strings.Split(*backupOf, ",")
+		creds, err := replicationCredentials(*certFile, *keyFile, *caFile)
+		if err != nil {
+			log.Fatalf("Server STARTUP_ERROR: %v", err)
+		}
+		chat.primary = primaries[0]
+		log.Printf("Server STARTUP: backup of %s", *backupOf)
+		go chat.follow(primaries, creds, addr, *takeoverAfter)
+	}
+	if *raftID != "" {
+		self, members, err := clusterMembers(*raftID, *raftPeers, *raftJoin, *raftAddr, *port)
+		if err != nil {
+			log.Fatalf("Server STARTUP_ERROR: %v", err)
+		}
+		creds, err := replicationCredentials(*certFile, *keyFile, *caFile)
+		if err != nil {
+			log.Fatalf("Server STARTUP_ERROR: %v", err)
+		}
+		node, err := chat.startCluster(*raftID, self, members, *raftJoin, *raftDir, *snapshotEvery, creds)
+		if err != nil {
+			log.Fatalf("Server STARTUP_ERROR: %v", err)
+		}
+		proto.RegisterRaftServer(grpcServer, raft.NewService(node, raftCaller, authorizeProposal))
+		log.Printf("Server STARTUP: cluster node %s at %s", *raftID, self.Addr)
+	}
+	if *serverName != "" {
+		chat.name = *serverName
+		chat.epoch = time.Now().UnixNano()
+		creds, err := replicationCredentials(*certFile, *keyFile, *caFile)
+		if err != nil {
+			log.Fatalf("Server STARTUP_ERROR: %v", err)
+		}
+		log.Printf("Server STARTUP: federating as %s", chat.name)
+		if *federateTo != "" {
+			for _, peer := range strings.Split(*federateTo, ",") {
+				go chat.federateWith(peer, creds)
+			}
+		}
+	}
+	if *heartbeat > 0 {
+		go chat.heartbeat(*heartbeat, *heartbeatMisses)
+	}
+	go chat.typingLoop()
 
 	// run server
 	go func() {
@@ -166,29 +550,165 @@ func main() {
 			log.Fatalf("Server ERROR: %v", err)
 		}
 	}()
-	//Block main goroutine -keep server running :)
-	select {}
+	//Block main goroutine until we are asked to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Printf("Server SHUTDOWN: signal received")
+	if chat.cluster != nil && *raftLeave {
+		chat.leaveCluster()
+	}
+	chat.shutdown("server is shutting down", *reconnectHint)
+	stopGracefully(grpcServer, *shutdownTimeout)
 }
 
-// Remove subscriber if client disconnects unexpectedly
-func (s *ChitChatServer) removeSubscriber(clientID string) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// room looks up the room a request is for. Must be called with s.mutex held
+func (s *ChitChatServer) room(name string) (*room, error) {
+	r, ok := s.rooms[roomName(name)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "room %q does not exist", name)
+	}
+	return r, nil
+}
 
-	// Check if subscriber exists before deleting
-	if _, exists := s.subscribers[clientID]; exists {
-		delete(s.subscribers, clientID)
-		s.timestamp++
+// emit persists a broadcast to the history and queues it for every subscriber in the room.
+// Must be called with s.mutex held
+func (s *ChitChatServer) emit(r *room, broadcast *proto.BroadCast) {
+	s.record(r, broadcast)
+	s.broadcast(r, broadcast, r.subscribers)
+}
 
-		// Optionally broadcast that they left unexpectedly
-		broadcast := &proto.BroadCast{
-			Type:      proto.BroadCast_LEAVE,
-			ClientId:  clientID,
-			Timestamp: s.timestamp,
+// record stamps the room on a broadcast and appends it to the history.
+// Must be called with s.mutex held
+func (s *ChitChatServer) record(r *room, broadcast *proto.BroadCast) {
+	broadcast.Room = r.name
+	// Everything but CHAT carries the rooms merged view in vector mode
+	if r.vector != nil && broadcast.Vector == nil {
+		broadcast.Vector = r.vector.Snapshot()
+	}
+	// Under federation every event of ours gets an ID peers can deduplicate by. It keeps
+	// growing across restarts, also when the history was not kept
+	if s.name != "" && broadcast.Origin == "" {
+		broadcast.Origin = s.name
+		broadcast.EventId = max(s.seen[s.name], s.epoch) + 1
+	}
+	//Clients can tell from it whether they missed something
+	broadcast.Previous = r.last
+	if err := s.history.append(broadcast); err != nil {
+		log.Printf("Server HISTORY_ERROR: failed to persist broadcast at logical time %d: %v", broadcast.Timestamp, err)
+	}
+	s.note(r, broadcast)
+	if s.name != "" && federates(broadcast) {
+		s.federate(broadcast)
+	}
+	s.replicate(&proto.ReplicationEvent{
+		Index:     int64(len(s.history.events)),
+		Broadcast: broadcast,
+		Room:      r.name,
+		Clock:     r.clock.Now(),
+	})
+}
+
+// note keeps everything the server knows besides the history up to date with a recorded
+// broadcast: the participants, messages and last broadcast of the room, the published
+// message IDs and the federation event IDs. A backup goes through it with every replicated
+// broadcast, so it knows the same once it takes over. Must be called with s.mutex held
+func (s *ChitChatServer) note(r *room, broadcast *proto.BroadCast) {
+	if broadcast.GetRecipient() == "" {
+		r.last = broadcast.GetTimestamp()
+	}
+	r.track(broadcast)
+	r.index(broadcast)
+	switch broadcast.GetType() {
+	case proto.BroadCast_CHAT, proto.BroadCast_DIRECT:
+		if broadcast.GetMessageId() != "" {
+			s.dedup.add(broadcast.GetClientId(), broadcast.GetMessageId())
 		}
+	}
+	if origin := broadcast.GetOrigin(); origin != "" && broadcast.GetEventId() > s.seen[origin] {
+		s.seen[origin] = broadcast.GetEventId()
+	}
+}
 
-		for _, subscriber := range s.subscribers {
-			subscriber.Send(broadcast)
+// senderVector returns the vector clock for a CHAT broadcast, nil in lamport mode.
+// Clients that send no vector (older ones) get a tick on their behalf
+func senderVector(r *room, clientID string, remote map[string]int64) map[string]int64 {
+	if r.vector == nil {
+		return nil
+	}
+	if len(remote) == 0 {
+		return r.vector.Tick(clientID)
+	}
+	//The senders own entry is counted here and not by the client, so a rejected
+	//or resent message leaves no gap in it
+	vector := make(map[string]int64, len(remote)+1)
+	for id, count := range remote {
+		if id != clientID {
+			vector[id] = count
 		}
 	}
+	r.vector.Merge(vector)
+	vector[clientID] = r.vector.Tick(clientID)[clientID]
+	return vector
+}
+
+// broadcast queues a message for the given subscribers of the room. Must be called with s.mutex held.
+// Subscribers that overflow under the disconnect policy are removed and a LEAVE is sent for them
+func (s *ChitChatServer) broadcast(r *room, broadcast *proto.BroadCast, targets map[string]*subscriber) {
+	var slow []*subscriber
+	for _, sub := range targets {
+		if !sub.enqueue(broadcast, s.overflow) {
+			slow = append(slow, sub)
+		}
+	}
+
+	for _, sub := range slow {
+		s.evict(r, sub, "too slow")
+	}
+}
+
+// evict removes a session from the room and sends a LEAVE once the participants last
+// session is gone, with the reason in the message. Must be called with s.mutex held
+func (s *ChitChatServer) evict(r *room, sub *subscriber, reason string) {
+	sub.close()
+	// A nested broadcast may already have removed it
+	if r.subscribers[sub.session] != sub {
+		return
+	}
+	delete(r.subscribers, sub.session)
+	// The rest of the cluster is still there when this node goes away
+	if s.cluster != nil && !r.online(sub.id) {
+		leave := &proto.BroadCast{Type: proto.BroadCast_LEAVE, ClientId: sub.id, Room: r.name}
+		if reason != "" {
+			leave.Message = "disconnected: " + reason
+		}
+		s.commitAsync(&proto.ChatCommand{Command: &proto.ChatCommand_Event{Event: leave}})
+		return
+	}
+	// Nobody is left to tell when the whole server goes away
+	if r.online(sub.id) || s.closing {
+		if reason != "" {
+			log.Printf("Session %s disconnected from room %s: %s", sub.session, r.name, reason)
+		}
+		return
+	}
+
+	leave := &proto.BroadCast{
+		Type:      proto.BroadCast_LEAVE,
+		ClientId:  sub.id,
+		Timestamp: r.clock.Tick(),
+	}
+	if reason != "" {
+		leave.Message = "disconnected: " + reason
+		log.Printf("Participant %s disconnected from room %s (%s) at logical time %d", sub.id, r.name, reason, leave.Timestamp)
+	}
+	s.emit(r, leave)
+}
+
+// removeSubscriber cleans up after a stream ended, reason is empty if the client went away on its own
+func (s *ChitChatServer) removeSubscriber(r *room, sub *subscriber, reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.evict(r, sub, reason)
 }
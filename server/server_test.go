@@ -0,0 +1,104 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTestServer is a server without history file, federation or cluster
+func newTestServer(t *testing.T) *ChitChatServer {
+	t.Helper()
+	h, err := openHistory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newChitChatServer(h, 16, dropOldest, false, 100)
+}
+
+// session subscribes clientID to the room without a stream and returns the context
+// of a call with its token
+func session(r *room, clientID string) context.Context {
+	sub := &subscriber{
+		id:      clientID,
+		session: clientID + "-session",
+		token:   clientID + "-token",
+		queue:   make(chan *proto.BroadCast, 16),
+		closed:  make(chan struct{}),
+	}
+	r.subscribers[sub.session] = sub
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(tokenHeader, sub.token))
+}
+
+func TestPublishMessageIDs(t *testing.T) {
+	type publish struct {
+		clientID, messageID string
+		code                codes.Code
+	}
+	tests := []struct {
+		name      string
+		dedupSize int
+		publishes []publish
+		posted    int
+	}{
+		{"different IDs", 100, []publish{{"alice", "a", codes.OK}, {"alice", "b", codes.OK}}, 2},
+		{"retry", 100, []publish{{"alice", "a", codes.OK}, {"alice", "a", codes.OK}}, 1},
+		{"retry after the window forgot it", 1, []publish{{"alice", "a", codes.OK}, {"alice", "b", codes.OK}, {"alice", "a", codes.OK}}, 2},
+		{"ID of someone else", 100, []publish{{"alice", "a", codes.OK}, {"bob", "a", codes.AlreadyExists}}, 1},
+		{"ID of someone else the window forgot", 1, []publish{{"alice", "a", codes.OK}, {"alice", "b", codes.OK}, {"bob", "a", codes.AlreadyExists}}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := openHistory("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := newChitChatServer(h, 16, dropOldest, false, test.dedupSize)
+			r := s.rooms[defaultRoom]
+			for _, p := range test.publishes {
+				_, _, err := s.publish(r, &proto.PublishRequest{ClientId: p.clientID, MessageId: p.messageID, Text: "hi", Room: defaultRoom})
+				if status.Code(err) != p.code {
+					t.Fatalf("%s publishing %s: %v, want %v", p.clientID, p.messageID, err, p.code)
+				}
+			}
+			if got := len(s.history.events); got != test.posted {
+				t.Fatalf("posted %d messages, want %d", got, test.posted)
+			}
+		})
+	}
+}
+
+func TestSenderVectorHasNoGaps(t *testing.T) {
+	h, err := openHistory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newChitChatServer(h, 16, dropOldest, true, 100)
+	s.validators = []validator{notEmpty{}}
+	r := s.rooms[defaultRoom]
+	publishes := []struct {
+		messageID, text string
+		vector          map[string]int64
+	}{
+		{"a", "hi", map[string]int64{"bob": 0}},
+		{"b", "", map[string]int64{"alice": 1}},      // rejected
+		{"a", "hi", map[string]int64{"alice": 2}},    // resent
+		{"c", "again", map[string]int64{"alice": 7}}, // ticked too often on the client
+	}
+	for _, p := range publishes {
+		if _, _, err := s.publish(r, &proto.PublishRequest{ClientId: "alice", MessageId: p.messageID, Text: p.text, Vector: p.vector, Room: defaultRoom}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []int64
+	for _, event := range s.history.events {
+		got = append(got, event.GetVector()["alice"])
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("alice's entries %v, want [1 2]", got)
+	}
+}
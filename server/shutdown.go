@@ -0,0 +1,57 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// shutdown tells every participant the server is going away, stops new Subscribe
+// calls and lets every outbound queue drain. The SERVER_SHUTDOWN broadcast is not
+// persisted and does not advance the clocks, it is not part of the chat
+func (s *ChitChatServer) shutdown(reason string, reconnectAfter time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closing = true
+	// Backups notice the primary is gone and take over
+	for f := range s.followers {
+		f.close()
+	}
+	for link := range s.peers {
+		link.close()
+	}
+	for _, r := range s.rooms {
+		broadcast := &proto.BroadCast{
+			Type:             proto.BroadCast_SERVER_SHUTDOWN,
+			Message:          reason,
+			Timestamp:        r.clock.Now(),
+			Room:             r.name,
+			ReconnectAfterMs: reconnectAfter.Milliseconds(),
+		}
+		s.broadcast(r, broadcast, r.subscribers)
+		for _, sub := range r.subscribers {
+			sub.drain()
+		}
+	}
+	log.Printf("Server SHUTDOWN: told everyone %q, draining queues", reason)
+}
+
+// stopGracefully waits for the streams to finish draining, but no longer than timeout
+func stopGracefully(grpcServer *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		log.Printf("Server SHUTDOWN: all streams drained")
+	case <-time.After(timeout):
+		log.Printf("Server SHUTDOWN: streams did not drain within %s, closing them", timeout)
+		grpcServer.Stop()
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// overflowPolicy decides what happens when a subscribers outbound queue is full
+type overflowPolicy int
+
+const (
+	dropOldest overflowPolicy = iota // throw away the oldest queued broadcast
+	dropNewest                       // throw away the broadcast that did not fit
+	disconnect                       // kick the slow client out of the chat
+)
+
+func parseOverflowPolicy(name string) (overflowPolicy, error) {
+	switch name {
+	case "drop-oldest":
+		return dropOldest, nil
+	case "drop-newest":
+		return dropNewest, nil
+	case "disconnect":
+		return disconnect, nil
+	}
+	return 0, fmt.Errorf("unknown overflow policy %q (use drop-oldest, drop-newest or disconnect)", name)
+}
+
+// outbound is the sending half of a Subscribe or Chat stream
+type outbound interface {
+	SendHeader(metadata.MD) error
+	Send(*proto.BroadCast) error
+}
+
+// subscriber wraps a client stream with its own bounded outbound queue.
+// A dedicated goroutine drains the queue, so a slow client only stalls itself
+type subscriber struct {
+	id      string // participant ID, shared by all devices of the same user
+	session string // unique per Subscribe call
+	token   string // secret the client proves it owns this session with
+	stream  outbound
+	queue   chan *proto.BroadCast
+	replay  []*proto.BroadCast // history sent before anything from the queue
+	closed  chan struct{}      // closed when the subscriber is removed from the chat
+	once    sync.Once
+
+	draining  chan struct{} // closed on shutdown: send what is queued, then stop
+	drainOnce sync.Once
+
+	lastSent atomic.Int64 // unix nanoseconds of the last successful Send
+}
+
+func newSubscriber(id, session, token string, stream outbound, size int, replay []*proto.BroadCast) *subscriber {
+	sub := &subscriber{
+		id:       id,
+		session:  session,
+		token:    token,
+		stream:   stream,
+		queue:    make(chan *proto.BroadCast, size),
+		replay:   replay,
+		closed:   make(chan struct{}),
+		draining: make(chan struct{}),
+	}
+	sub.lastSent.Store(time.Now().UnixNano())
+	return sub
+}
+
+// enqueue puts a broadcast on the outbound queue without ever blocking.
+// It returns false if the subscriber should be disconnected because of the policy
+func (sub *subscriber) enqueue(broadcast *proto.BroadCast, policy overflowPolicy) bool {
+	select {
+	case sub.queue <- broadcast:
+		return true
+	default:
+	}
+
+	switch policy {
+	case dropNewest:
+		log.Printf("Server QUEUE_FULL: dropping newest broadcast for %s", sub.session)
+		return true
+	case dropOldest:
+		log.Printf("Server QUEUE_FULL: dropping oldest broadcast for %s", sub.session)
+		select {
+		case <-sub.queue:
+		default:
+		}
+		select {
+		case sub.queue <- broadcast:
+		default:
+		}
+		return true
+	}
+	return false
+}
+
+// run sends the replayed history and then queued broadcasts to the client
+// until the subscriber is closed or Send fails
+func (sub *subscriber) run() error {
+	for _, broadcast := range sub.replay {
+		if err := sub.send(broadcast); err != nil {
+			return err
+		}
+	}
+	sub.replay = nil
+
+	for {
+		select {
+		case <-sub.closed:
+			return nil
+		case <-sub.draining:
+			return sub.flush()
+		case broadcast := <-sub.queue:
+			if err := sub.send(broadcast); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// send writes one broadcast to the stream and remembers when it got through
+func (sub *subscriber) send(broadcast *proto.BroadCast) error {
+	if err := sub.stream.Send(broadcast); err != nil {
+		return err
+	}
+	sub.lastSent.Store(time.Now().UnixNano())
+	return nil
+}
+
+// idle tells how long nothing got through to the client
+func (sub *subscriber) idle(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, sub.lastSent.Load()))
+}
+
+// flush sends everything still queued without waiting for more
+func (sub *subscriber) flush() error {
+	for {
+		select {
+		case broadcast := <-sub.queue:
+			if err := sub.send(broadcast); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// drain makes the sender goroutine empty the queue and stop, safe to call more than once
+func (sub *subscriber) drain() {
+	sub.drainOnce.Do(func() { close(sub.draining) })
+}
+
+// close stops the sender goroutine, safe to call more than once
+func (sub *subscriber) close() {
+	sub.once.Do(func() { close(sub.closed) })
+}
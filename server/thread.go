@@ -0,0 +1,68 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetThread returns a message and every reply to it, as they read now
+func (s *ChitChatServer) GetThread(ctx context.Context, req *proto.GetThreadRequest) (*proto.GetThreadResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	r, err := s.room(req.GetRoom())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+		return nil, err
+	}
+	messageID := req.GetMessageId()
+	p, ok := r.messages[messageID]
+	if !ok || !visibleTo(p.message, req.GetClientId()) {
+		return nil, status.Errorf(codes.NotFound, "no message %q in room %s", messageID, r.name)
+	}
+
+	response := &proto.GetThreadResponse{Message: p.current}
+	for _, id := range p.replies {
+		if reply := r.messages[id]; visibleTo(reply.message, req.GetClientId()) {
+			response.Replies = append(response.Replies, reply.current)
+		}
+	}
+	sort.SliceStable(response.Replies, func(i, j int) bool {
+		return response.Replies[i].GetTimestamp() < response.Replies[j].GetTimestamp()
+	})
+	return response, nil
+}
+
+// checkParent makes sure a reply answers a message the sender could see. A reply to a
+// private message stays between the same two people. Must be called with s.mutex held
+func (s *ChitChatServer) checkParent(r *room, req *proto.PublishRequest) error {
+	parentID := req.GetParentId()
+	if parentID == "" {
+		return nil
+	}
+	if parentID == req.GetMessageId() {
+		return status.Error(codes.InvalidArgument, "a message cannot reply to itself")
+	}
+	parent, deleted := s.message(r, parentID)
+	if parent == nil || !visibleTo(parent, req.GetClientId()) {
+		return status.Errorf(codes.NotFound, "no message %q to reply to in room %s", parentID, r.name)
+	}
+	if deleted {
+		return status.Errorf(codes.FailedPrecondition, "message %q was deleted", parentID)
+	}
+	if parent.GetType() == proto.BroadCast_DIRECT {
+		other := parent.GetClientId()
+		if other == req.GetClientId() {
+			other = parent.GetRecipient()
+		}
+		if req.GetRecipient() != other {
+			return status.Errorf(codes.InvalidArgument, "message %q is private, reply to it with /msg %s", parentID, other)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetThread(t *testing.T) {
+	s := newTestServer(t)
+	r := s.rooms[defaultRoom]
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "alice", Message: "lunch?", MessageId: "m1", Timestamp: 1})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "bob", Message: "yes", MessageId: "m2", ParentId: "m1", Timestamp: 2})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_DIRECT, ClientId: "carol", Recipient: "alice", Message: "not me", MessageId: "m3", ParentId: "m1", Timestamp: 3})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_CHAT, ClientId: "bob", Message: "unrelated", MessageId: "m4", Timestamp: 4})
+	s.emit(r, &proto.BroadCast{Type: proto.BroadCast_DIRECT, ClientId: "carol", Recipient: "alice", Message: "secret", MessageId: "m5", Timestamp: 5})
+	s.deliverAmend(r, r.messages["m2"].message, &proto.BroadCast{Type: proto.BroadCast_EDIT, ClientId: "bob", Message: "yes!", MessageId: "m2", Timestamp: 6})
+	s.deliverAmend(r, r.messages["m1"].message, &proto.BroadCast{Type: proto.BroadCast_DELETE, ClientId: "alice", MessageId: "m1", Timestamp: 7})
+
+	tests := []struct {
+		name      string
+		clientID  string
+		messageID string
+		code      codes.Code
+		replies   []string // texts as they read now
+	}{
+		{"author sees the private reply", "alice", "m1", codes.OK, []string{"yes!", "not me"}},
+		{"sender sees the private reply", "carol", "m1", codes.OK, []string{"yes!", "not me"}},
+		{"others do not", "bob", "m1", codes.OK, []string{"yes!"}},
+		{"no replies", "bob", "m4", codes.OK, nil},
+		{"private message of others", "bob", "m5", codes.NotFound, nil},
+		{"unknown", "bob", "m9", codes.NotFound, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := s.GetThread(session(r, test.clientID), &proto.GetThreadRequest{ClientId: test.clientID, Room: defaultRoom, MessageId: test.messageID})
+			if status.Code(err) != test.code {
+				t.Fatalf("GetThread = %v, want %v", err, test.code)
+			}
+			if err != nil {
+				return
+			}
+			if test.messageID == "m1" && !response.Message.GetDeleted() {
+				t.Fatal("the delete is not folded into the message")
+			}
+			var got []string
+			for _, reply := range response.Replies {
+				got = append(got, reply.GetMessage())
+			}
+			if len(got) != len(test.replies) {
+				t.Fatalf("replies %q, want %q", got, test.replies)
+			}
+			for i := range got {
+				if got[i] != test.replies[i] {
+					t.Fatalf("replies %q, want %q", got, test.replies)
+				}
+			}
+		})
+	}
+	if r.messages["m1"].message.GetDeleted() {
+		t.Fatal("the delete changed the message in the history")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	typingTimeout  = 6 * time.Second        // typing stops when the client does not repeat the signal for this long
+	typingInterval = 250 * time.Millisecond // how often typing changes are looked at and broadcast
+	typingMinGap   = time.Second            // least time between two broadcasts about the same participant
+)
+
+// typist is what the server knows about a participant typing in a room
+type typist struct {
+	until    time.Time // typing until then, zero once they stopped
+	shown    bool      // the room was told they are typing
+	lastSent time.Time // last TYPING_START or TYPING_STOP about them
+}
+
+// Typing records that a participant started or stopped typing, the typing loop tells the room.
+// It stays on this server: typing is not committed to the cluster, replicated or federated
+func (s *ChitChatServer) Typing(ctx context.Context, req *proto.TypingRequest) (*proto.TypingResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	r, err := s.room(req.GetRoom())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := authorize(ctx, r, req.GetClientId()); err != nil {
+		return nil, err
+	}
+	s.setTyping(r, req.GetClientId(), req.GetTyping())
+	return &proto.TypingResponse{Ack: true}, nil
+}
+
+// chatTyping is Typing for the session of a Chat stream
+func (s *ChitChatServer) chatTyping(r *room, sub *subscriber, req *proto.TypingRequest) error {
+	if req.GetClientId() != "" && req.GetClientId() != sub.id {
+		return status.Errorf(codes.PermissionDenied, "this stream belongs to %s", sub.id)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if r.subscribers[sub.session] != sub {
+		return status.Errorf(codes.FailedPrecondition, "session %s is no longer in room %s", sub.session, r.name)
+	}
+	s.setTyping(r, sub.id, req.GetTyping())
+	return nil
+}
+
+// setTyping only notes the signal, however often it comes, so a client cannot flood
+// the room. Must be called with s.mutex held
+func (s *ChitChatServer) setTyping(r *room, clientID string, typing bool) {
+	t, ok := r.typing[clientID]
+	if !ok {
+		if !typing {
+			return
+		}
+		t = &typist{}
+		r.typing[clientID] = t
+	}
+	if typing {
+		t.until = time.Now().Add(typingTimeout)
+		r.active(clientID)
+	} else {
+		t.until = time.Time{}
+	}
+}
+
+// typingLoop broadcasts TYPING_START and TYPING_STOP when someone started or stopped
+// typing since the last round. Changes that come faster than typingMinGap are coalesced.
+// Like heartbeats they are not persisted, not replicated and do not advance the clocks
+func (s *ChitChatServer) typingLoop() {
+	ticker := time.NewTicker(typingInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.mutex.Lock()
+		if s.closing {
+			s.mutex.Unlock()
+			return
+		}
+		for _, r := range s.rooms {
+			for clientID, t := range r.typing {
+				typing := now.Before(t.until) && r.online(clientID)
+				if typing == t.shown {
+					if !typing {
+						delete(r.typing, clientID)
+					}
+					continue
+				}
+				if now.Sub(t.lastSent) < typingMinGap {
+					continue
+				}
+				t.shown = typing
+				t.lastSent = now
+				s.broadcastTyping(r, clientID, typing)
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// broadcastTyping tells everyone in the room but the typist. Must be called with s.mutex held
+func (s *ChitChatServer) broadcastTyping(r *room, clientID string, typing bool) {
+	event := proto.BroadCast_TYPING_STOP
+	if typing {
+		event = proto.BroadCast_TYPING_START
+	}
+	targets := make(map[string]*subscriber)
+	for session, sub := range r.subscribers {
+		if sub.id != clientID {
+			targets[session] = sub
+		}
+	}
+	s.broadcast(r, &proto.BroadCast{
+		Type:      event,
+		ClientId:  clientID,
+		Timestamp: r.clock.Now(),
+		Room:      r.name,
+	}, targets)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// rejection is why a validation step turned a message down
+type rejection struct {
+	code   proto.PublishError
+	reason string
+}
+
+func (r *rejection) Error() string {
+	return fmt.Sprintf("%s: %s", r.code, r.reason)
+}
+
+// validator is one step of the validation chain every published or edited text goes
+// through. A step may rewrite the text, the next one gets what it returned
+type validator interface {
+	validate(text string) (string, *rejection)
+}
+
+// validate runs the text through every step in order and returns it as it will be posted
+func validate(chain []validator, text string) (string, *rejection) {
+	for _, step := range chain {
+		var rejected *rejection
+		if text, rejected = step.validate(text); rejected != nil {
+			return "", rejected
+		}
+	}
+	return text, nil
+}
+
+// validUTF8 rejects text that is not valid UTF-8, the later steps count runes. gRPC
+// usually turns such a request down before it gets here
+type validUTF8 struct{}
+
+func (validUTF8) validate(text string) (string, *rejection) {
+	if !utf8.ValidString(text) {
+		return "", &rejection{proto.PublishError_INVALID_UTF8, "message is not valid UTF-8"}
+	}
+	return text, nil
+}
+
+// stripInvisible removes control characters but tab and newline, and the bidi controls
+// that can make text read differently than it is stored
+type stripInvisible struct{}
+
+func (stripInvisible) validate(text string) (string, *rejection) {
+	return strings.Map(func(c rune) rune {
+		if (unicode.IsControl(c) && c != '\t' && c != '\n') || isBidiControl(c) {
+			return -1
+		}
+		return c
+	}, text), nil
+}
+
+func isBidiControl(c rune) bool {
+	switch {
+	case c == '\u061c', c == '\u200e', c == '\u200f': // arabic letter mark, left-to-right and right-to-left mark
+		return true
+	case c >= '\u202a' && c <= '\u202e': // embeddings and overrides
+		return true
+	case c >= '\u2066' && c <= '\u2069': // isolates
+		return true
+	}
+	return false
+}
+
+// notEmpty rejects text that is only white space
+type notEmpty struct{}
+
+func (notEmpty) validate(text string) (string, *rejection) {
+	if strings.TrimSpace(text) == "" {
+		return "", &rejection{proto.PublishError_EMPTY_MESSAGE, "message is empty"}
+	}
+	return text, nil
+}
+
+// maxRunes limits the length in characters, an emoji is one
+type maxRunes int
+
+func (m maxRunes) validate(text string) (string, *rejection) {
+	if n := utf8.RuneCountInString(text); n > int(m) {
+		return "", &rejection{proto.PublishError_TOO_MANY_CHARACTERS, fmt.Sprintf("message has %d characters, at most %d are allowed", n, m)}
+	}
+	return text, nil
+}
+
+// maxBytes limits the encoded size, whatever the characters
+type maxBytes int
+
+func (m maxBytes) validate(text string) (string, *rejection) {
+	if len(text) > int(m) {
+		return "", &rejection{proto.PublishError_TOO_MANY_BYTES, fmt.Sprintf("message has %d bytes, at most %d are allowed", len(text), m)}
+	}
+	return text, nil
+}
+
+// blockedWords rejects text containing one of the words, ignoring case
+type blockedWords map[string]bool
+
+func newBlockedWords(words []string) blockedWords {
+	blocked := make(blockedWords)
+	for _, word := range words {
+		blocked[strings.ToLower(word)] = true
+	}
+	return blocked
+}
+
+func (b blockedWords) validate(text string) (string, *rejection) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	})
+	for _, word := range words {
+		if b[word] {
+			return "", &rejection{proto.PublishError_BLOCKED_WORD, fmt.Sprintf("message contains the blocked word %q", word)}
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	proto "ChitChat/grpc"
+	"strings"
+	"testing"
+)
+
+func TestValidationChain(t *testing.T) {
+	chain, err := defaultConfig().Validation.chain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain = append(chain, newBlockedWords([]string{"Spam"}))
+
+	tests := []struct {
+		name string
+		text string
+		want string
+		code proto.PublishError
+	}{
+		{"plain", "hello", "hello", proto.PublishError_NO_ERROR},
+		{"invalid UTF-8", "a\xffb", "", proto.PublishError_INVALID_UTF8},
+		{"tab and newline stay", "a\tb\nc", "a\tb\nc", proto.PublishError_NO_ERROR},
+		{"control characters go", "a\x00b\x1bc", "abc", proto.PublishError_NO_ERROR},
+		{"bidi override goes", "abc\u202edef", "abcdef", proto.PublishError_NO_ERROR},
+		{"empty", "", "", proto.PublishError_EMPTY_MESSAGE},
+		{"only spaces", "  \t\n ", "", proto.PublishError_EMPTY_MESSAGE},
+		{"empty once stripped", "\u202e\x07", "", proto.PublishError_EMPTY_MESSAGE},
+		{"128 emoji", strings.Repeat("🙂", 128), strings.Repeat("🙂", 128), proto.PublishError_NO_ERROR},
+		{"129 characters", strings.Repeat("a", 129), "", proto.PublishError_TOO_MANY_CHARACTERS},
+		{"blocked word in any case", "buy SPAM now", "", proto.PublishError_BLOCKED_WORD},
+		{"blocked word inside another", "spammer", "spammer", proto.PublishError_NO_ERROR},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, rejected := validate(chain, test.text)
+			if rejected != nil {
+				if rejected.code != test.code {
+					t.Fatalf("rejected with %v, want %v", rejected.code, test.code)
+				}
+				return
+			}
+			if test.code != proto.PublishError_NO_ERROR {
+				t.Fatalf("passed as %q, want %v", got, test.code)
+			}
+			if got != test.want {
+				t.Fatalf("posted as %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidationOrder(t *testing.T) {
+	// Bytes are counted before stripping when max_bytes comes first
+	config := validationConfig{Steps: []string{"max_bytes", "strip"}, MaxBytes: 4}
+	chain, err := config.chain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, rejected := validate(chain, "ab\x00\x00\x00"); rejected == nil || rejected.code != proto.PublishError_TOO_MANY_BYTES {
+		t.Fatalf("rejected = %v, want TOO_MANY_BYTES", rejected)
+	}
+
+	config.Steps = []string{"strip", "max_bytes"}
+	if chain, err = config.chain(); err != nil {
+		t.Fatal(err)
+	}
+	if got, rejected := validate(chain, "ab\x00\x00\x00"); rejected != nil || got != "ab" {
+		t.Fatalf("validate = %q, %v, want \"ab\"", got, rejected)
+	}
+}
+
+func TestValidationConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config validationConfig
+		ok     bool
+	}{
+		{"defaults", defaultConfig().Validation, true},
+		{"no steps", validationConfig{}, true},
+		{"utf8 step", validationConfig{Steps: []string{"utf8"}}, true},
+		{"unknown step", validationConfig{Steps: []string{"spellcheck"}}, false},
+		{"max_runes without a limit", validationConfig{Steps: []string{"max_runes"}}, false},
+		{"max_bytes without a limit", validationConfig{Steps: []string{"max_bytes"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.config.chain(); (err == nil) != test.ok {
+				t.Fatalf("chain() = %v, want ok=%v", err, test.ok)
+			}
+		})
+	}
+}